@@ -3,7 +3,8 @@ package devwatch
 import (
 	"os"
 	"path/filepath"
-	"slices"
+
+	"github.com/tinywasm/depfind"
 )
 
 // addDirectoryToWatcher adds a directory to the watcher and handles folder events
@@ -64,6 +65,8 @@ func (h *DevWatch) InitialRegistration() {
 	}
 	h.noAddMu.Unlock()
 
+	h.watchGitDir()
+
 	reg := make(map[string]struct{})
 
 	err := filepath.Walk(h.AppRootDir, func(path string, info os.FileInfo, err error) error {
@@ -80,18 +83,35 @@ func (h *DevWatch) InitialRegistration() {
 				return nil // Skip ignored files
 			}
 
+			// Test files are not part of any build, test handlers
+			// only hear about them once they change
+			if isTestFile(path) && !h.BuildTestFiles {
+				return nil
+			}
+
 			// Process existing files during initial registration
 			fileName, ferr := GetFileName(path)
 			if ferr == nil {
 				extension := filepath.Ext(path)
 
+				// known imports: the first save of a file only rebuilds the graph if they change
+				if extension == ".go" && !isTestFile(path) {
+					h.rememberImports(path)
+				}
+
 				for _, handler := range h.FilesEventHandlers {
-					if slices.Contains(handler.SupportedExtensions(), extension) {
+					// existing files register whatever the handler's EventsFilter
+					if handlesExtension(handler, extension) && initialFiles(handler) {
 						var isMine = true
 						var herr error
 
 						if extension == ".go" {
-							isMine, herr = h.depFinder.ThisFileIsMine(handler.MainInputFileRelativePath(), path, "create")
+							if allGoFiles(handler) || !buildMatches(handler, path) {
+								continue // not tied to a main package or excluded by build constraints
+							}
+							h.withDeps(func(finder *depfind.GoDepFind) {
+								isMine, herr = finder.ThisFileIsMine(handler.MainInputFileRelativePath(), path, "create")
+							})
 							if herr != nil {
 								//h.Logger("InitialRegistration go file error:", herr)
 								continue // Skip on error
@@ -106,6 +126,18 @@ func (h *DevWatch) InitialRegistration() {
 						}
 					}
 				}
+
+				// Embedded files also register with the Go handlers that embed them
+				if extension != ".go" {
+					for _, handler := range h.embedOwners(path) {
+						if handlesExtension(handler, extension) || !initialFiles(handler) {
+							continue
+						}
+						if err := handler.NewFileEvent(fileName, extension, path, "create"); err != nil {
+							h.Logger("InitialRegistration embedded file error:", err)
+						}
+					}
+				}
 			}
 		}
 		return nil
@@ -0,0 +1,18 @@
+package devwatch
+
+// Stats reports the size of the watcher's internal state.
+type Stats struct {
+	TrackedFiles     int // per-file debounce entries, bounded by MaxTrackedFiles and TrackedFileTTL
+	DispatchedFiles  int // contents at the last dispatch, bounded by MaxTrackedFiles, see SkipUnchangedWrites
+	HashCacheEntries int // cached content hashes keyed by file stamp
+}
+
+// Stats returns a snapshot of the watcher's internal state sizes.
+func (h *DevWatch) Stats() Stats {
+	h.initFileState()
+	return Stats{
+		TrackedFiles:     h.eventState.Len(),
+		DispatchedFiles:  h.dispatchedState.Len(),
+		HashCacheEntries: h.hashCache.Len(),
+	}
+}
@@ -0,0 +1,57 @@
+package devwatch
+
+import (
+	"go/build"
+	"path/filepath"
+	"runtime"
+)
+
+// BuildTarget is the platform and build tags a Go handler compiles for.
+type BuildTarget struct {
+	GOOS   string   // default: the host GOOS eg: "js"
+	GOARCH string   // default: the host GOARCH eg: "wasm"
+	Tags   []string // extra build tags eg: ["dev"]
+}
+
+// HandlerBuildTarget is optionally implemented by Go handlers so .go files
+// excluded from their target by //go:build lines or _GOOS/_GOARCH filename
+// suffixes are not routed to them, eg: a wasm handler returns {GOOS: "js", GOARCH: "wasm"}.
+type HandlerBuildTarget interface {
+	BuildTarget() BuildTarget
+}
+
+// context returns the go/build context of the target
+func (t BuildTarget) context() build.Context {
+	ctx := build.Default
+	if t.GOOS != "" {
+		ctx.GOOS = t.GOOS
+	}
+	if t.GOARCH != "" {
+		ctx.GOARCH = t.GOARCH
+	}
+	// cgo is only available when building for the host
+	if ctx.GOOS != runtime.GOOS || ctx.GOARCH != runtime.GOARCH {
+		ctx.CgoEnabled = false
+	}
+	ctx.BuildTags = append(ctx.BuildTags, t.Tags...)
+	return ctx
+}
+
+// buildMatches reports whether the .go file at path is part of the handler's
+// build target. Handlers without a BuildTarget accept every file, and so do
+// files that can't be read, eg: removed ones.
+func buildMatches(handler FilesEventHandlers, path string) bool {
+	bt, ok := handler.(HandlerBuildTarget)
+	if !ok {
+		return true
+	}
+	return targetMatches(bt.BuildTarget(), path)
+}
+
+// targetMatches reports whether the .go file at path is part of target,
+// files that can't be read included
+func targetMatches(target BuildTarget, path string) bool {
+	ctx := target.context()
+	match, err := ctx.MatchFile(filepath.Dir(path), filepath.Base(path))
+	return err != nil || match
+}
@@ -0,0 +1,105 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TargetHandler is a RecordingHandler building for a given target
+type TargetHandler struct {
+	RecordingHandler
+	Target BuildTarget
+}
+
+func (h *TargetHandler) BuildTarget() BuildTarget {
+	return h.Target
+}
+
+func TestBuildMatches(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"plain.go":    "package dom\n",
+		"dom.go":      "//go:build js && wasm\n\npackage dom\n",
+		"fs_linux.go": "package dom\n",
+		"dev.go":      "//go:build dev\n\npackage dom\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wasm := &TargetHandler{Target: BuildTarget{GOOS: "js", GOARCH: "wasm"}}
+	linux := &TargetHandler{Target: BuildTarget{GOOS: "linux", GOARCH: "amd64"}}
+	linuxDev := &TargetHandler{Target: BuildTarget{GOOS: "linux", GOARCH: "amd64", Tags: []string{"dev"}}}
+	noTarget := &RecordingHandler{}
+
+	tests := []struct {
+		name    string
+		handler FilesEventHandlers
+		file    string
+		want    bool
+	}{
+		{"plain file for wasm", wasm, "plain.go", true},
+		{"build line matches", wasm, "dom.go", true},
+		{"build line excludes", linux, "dom.go", false},
+		{"filename suffix matches", linux, "fs_linux.go", true},
+		{"filename suffix excludes", wasm, "fs_linux.go", false},
+		{"tag missing", linux, "dev.go", false},
+		{"tag given", linuxDev, "dev.go", true},
+		{"no target accepts all", noTarget, "dom.go", true},
+		{"removed file checked by suffix", wasm, "gone_linux.go", false},
+		{"removed file without suffix", wasm, "gone.go", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildMatches(tt.handler, filepath.Join(dir, tt.file)); got != tt.want {
+				t.Errorf("buildMatches(%s) = %v; want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleFileEvent_BuildConstraints(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fixture uses a _linux.go file of the host")
+	}
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod":               "module example\n\ngo 1.22\n",
+		"cmd/app/main.go":      "package main\n\nimport \"example/pkg/fs\"\n\nfunc main() { fs.Open() }\n",
+		"pkg/fs/fs.go":         "package fs\n\nfunc Open() { open() }\n",
+		"pkg/fs/open_linux.go": "package fs\n\nfunc open() {}\n",
+	})
+
+	server := &TargetHandler{
+		RecordingHandler: RecordingHandler{Name_: "server", MainInput: "cmd/app/main.go", Extensions: []string{".go"}},
+		Target:           BuildTarget{GOOS: "linux", GOARCH: "amd64"},
+	}
+	wasm := &TargetHandler{
+		RecordingHandler: RecordingHandler{Name_: "wasm", MainInput: "cmd/app/main.go", Extensions: []string{".go"}},
+		Target:           BuildTarget{GOOS: "js", GOARCH: "wasm"},
+	}
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, wasm},
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	linuxFile := filepath.Join(root, "pkg/fs/open_linux.go")
+	w.handleFileEvent("open_linux.go", linuxFile, OpWrite, false)
+	w.handleFileEvent("fs.go", filepath.Join(root, "pkg/fs/fs.go"), OpWrite, false)
+
+	if got := server.Files(); len(got) != 2 {
+		t.Errorf("server received %v; want both files", got)
+	}
+	if got := wasm.Files(); len(got) != 1 || got[0] != "fs.go:write" {
+		t.Errorf("wasm received %v; want only fs.go", got)
+	}
+	if got := w.OwnersOf(linuxFile); len(got) != 1 || got[0] != "server" {
+		t.Errorf("OwnersOf(open_linux.go) = %v; want [server]", got)
+	}
+}
@@ -0,0 +1,298 @@
+// Package command provides a devwatch handler that runs a command when
+// files matching its patterns change.
+//
+// The command arguments may use the placeholders {file}, {dir}, {ext} and
+// {event} of the changed file:
+//
+//	templ := command.New("templ", rootDir, "templ", "generate", "-f", "{file}")
+//	templ.Patterns = []string{"*.templ"}
+//	templ.Logger = logger
+//
+//	sqlc := command.New("sqlc", rootDir, "sqlc", "generate")
+//	sqlc.Patterns = []string{"db/queries/", "sqlc.yaml"}
+//	sqlc.Extensions = []string{".sql", ".yaml"}
+//	sqlc.Debounce = 300 * time.Millisecond
+//
+//	cfg := &devwatch.WatchConfig{
+//		FilesEventHandlers: []devwatch.FilesEventHandlers{server, templ, sqlc},
+//	}
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinywasm/devwatch"
+	"github.com/tinywasm/devwatch/internal/linelog"
+)
+
+// DefaultWaitDelay is how long a cancelled run may take to stop after the
+// interrupt before it is killed.
+const DefaultWaitDelay = 3 * time.Second
+
+// Handler runs Command for every change of a file matching Patterns.
+// Runs are asynchronous and never reload the browser: files the command
+// writes in the watched tree are picked up as changes of their own.
+// The files existing at startup don't run it, only later changes do.
+type Handler struct {
+	RootDir        string               // paths in Patterns are relative to it
+	Command        []string             // program and arguments eg: ["templ", "generate", "-f", "{file}"]
+	Patterns       []string             // a directory matches every file below it, a pattern without "/" matches the file name eg: ["*.templ", "assets/fonts/"]
+	Extensions     []string             // default: the extensions of Patterns eg: "*.templ" → ".templ", every extension if one has none
+	Events         []string             // default: every watched event eg: ["create", "write"]
+	Dir            string               // working directory. default: RootDir
+	Env            []string             // added to the environment eg: ["CGO_ENABLED=0"]
+	Debounce       time.Duration        // wait for the changes to settle, identical commands in the window run once
+	CancelPrevious bool                 // a new run interrupts the one in progress instead of waiting for it
+	WaitDelay      time.Duration        // see DefaultWaitDelay
+	Logger         func(message ...any) // command output and failures
+
+	name string
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer // debounced runs by expanded command
+	base    context.Context        // parent of the queued runs, cancelled by Stop
+	stopAll context.CancelFunc
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// New returns a Handler named name running program with args.
+func New(name, rootDir, program string, args ...string) *Handler {
+	return &Handler{
+		RootDir: rootDir,
+		Command: append([]string{program}, args...),
+		name:    name,
+	}
+}
+
+func (c *Handler) Name() string                        { return c.name }
+func (c *Handler) MainInputFileRelativePath() string   { return "" }
+func (c *Handler) UnobservedFiles() []string           { return nil }
+func (c *Handler) AllGoFiles() bool                    { return true }
+func (c *Handler) ReloadPolicy() devwatch.ReloadPolicy { return devwatch.ReloadNever }
+func (c *Handler) InitialFiles() bool                  { return false }
+
+// SupportedExtensions returns Extensions or the extensions of Patterns.
+func (c *Handler) SupportedExtensions() []string {
+	if len(c.Extensions) > 0 {
+		return c.Extensions
+	}
+	exts, _ := c.patternExtensions()
+	return exts
+}
+
+// AllExtensions reports whether every file must reach the handler: Extensions
+// is empty and a pattern has no extension, eg: "assets/fonts/", or there are
+// no patterns. Patterns still select the files.
+func (c *Handler) AllExtensions() bool {
+	if len(c.Extensions) > 0 {
+		return false
+	}
+	_, all := c.patternExtensions()
+	return all
+}
+
+// patternExtensions returns the extensions of Patterns and whether some
+// pattern, or the lack of patterns, matches any extension
+func (c *Handler) patternExtensions() (exts []string, all bool) {
+	if len(c.Patterns) == 0 {
+		return nil, true
+	}
+	for _, pattern := range c.Patterns {
+		ext := path.Ext(pattern)
+		if ext == "" || strings.ContainsAny(ext, "*?[") {
+			all = true
+			continue
+		}
+		if !slices.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	return exts, all
+}
+
+// SupportedEvents returns Events, or every event when it is empty.
+func (c *Handler) SupportedEvents() []string {
+	if len(c.Events) > 0 {
+		return c.Events
+	}
+	return []string{devwatch.OpCreate, devwatch.OpWrite, devwatch.OpRemove, devwatch.OpRename, devwatch.OpChmod}
+}
+
+// NewFileEvent schedules the command when filePath matches Patterns.
+func (c *Handler) NewFileEvent(fileName, extension, filePath, event string) error {
+	if len(c.Command) == 0 {
+		return errors.New("command: empty command")
+	}
+	if !c.matches(filePath) {
+		return nil
+	}
+	args := c.expand(filePath, event)
+	if c.Debounce <= 0 {
+		c.start(args)
+		return nil
+	}
+
+	key := strings.Join(args, "\x00")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == nil {
+		c.pending = map[string]*time.Timer{}
+	}
+	if t, ok := c.pending[key]; ok {
+		t.Stop()
+	}
+	c.pending[key] = time.AfterFunc(c.Debounce, func() {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+		c.start(args)
+	})
+	return nil
+}
+
+// Wait blocks until the current run, if any, has finished.
+// Debounced runs that haven't started yet are not waited for.
+func (c *Handler) Wait() {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Stop drops the debounced runs, cancels the current one and waits for it to exit.
+func (c *Handler) Stop() {
+	c.mu.Lock()
+	for key, t := range c.pending {
+		t.Stop()
+		delete(c.pending, key)
+	}
+	if c.stopAll != nil {
+		c.stopAll()
+		c.base, c.stopAll = nil, nil
+	}
+	c.mu.Unlock()
+	c.Wait()
+}
+
+// matches reports whether filePath is selected by Patterns, every file
+// is when there are none
+func (c *Handler) matches(filePath string) bool {
+	if len(c.Patterns) == 0 {
+		return true
+	}
+	rel, err := filepath.Rel(c.RootDir, filePath)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range c.Patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if rel == pattern || strings.HasPrefix(rel, pattern+"/") {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expand replaces the placeholders of Command with the values of the event
+func (c *Handler) expand(filePath, event string) []string {
+	r := strings.NewReplacer(
+		"{file}", filePath,
+		"{dir}", filepath.Dir(filePath),
+		"{ext}", filepath.Ext(filePath),
+		"{event}", event,
+	)
+	args := make([]string, len(c.Command))
+	for i, arg := range c.Command {
+		args[i] = r.Replace(arg)
+	}
+	return args
+}
+
+// start runs args once the previous run has exited, cancelling it first
+// when CancelPrevious is set
+func (c *Handler) start(args []string) {
+	c.mu.Lock()
+	if c.CancelPrevious && c.cancel != nil {
+		c.cancel()
+	}
+	if c.base == nil {
+		c.base, c.stopAll = context.WithCancel(context.Background())
+	}
+	prev := c.done
+	ctx, cancel := context.WithCancel(c.base)
+	done := make(chan struct{})
+	c.cancel, c.done = cancel, done
+	c.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		if prev != nil {
+			<-prev
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		c.run(ctx, args)
+	}()
+}
+
+// run executes the command, logging its output line by line
+func (c *Handler) run(ctx context.Context, args []string) {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = c.Dir
+	if cmd.Dir == "" {
+		cmd.Dir = c.RootDir
+	}
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = c.WaitDelay
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = DefaultWaitDelay
+	}
+	out := linelog.New(c.log, c.name)
+	cmd.Stdout, cmd.Stderr = out, out
+
+	err := cmd.Run()
+	out.Flush()
+	switch {
+	case ctx.Err() != nil:
+		c.log(c.name, "cancelled")
+	case err != nil:
+		c.log(c.name, "failed:", err)
+	}
+}
+
+func (c *Handler) log(message ...any) {
+	if c.Logger != nil {
+		c.Logger(message...)
+	}
+}
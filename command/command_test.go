@@ -0,0 +1,270 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/devwatch"
+)
+
+// logRecorder collects the logged lines
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logRecorder) log(message ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, strings.TrimSuffix(fmt.Sprintln(message...), "\n"))
+}
+
+func (l *logRecorder) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.lines)
+}
+
+func newHandler(t *testing.T, root string, program string, args ...string) (*Handler, *logRecorder) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	logs := &logRecorder{}
+	c := New("cmd", root, program, args...)
+	c.Logger = logs.log
+	t.Cleanup(c.Stop)
+	return c, logs
+}
+
+func TestHandler_Matches(t *testing.T) {
+	root := t.TempDir()
+	c := New("cmd", root, "true")
+	c.Patterns = []string{"*.templ", "assets/fonts/", "db/*.sql"}
+
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"views/home.templ", true},
+		{"home.templ", true},
+		{"assets/fonts/inter.woff2", true},
+		{"assets/fonts", true},
+		{"assets/img/logo.png", false},
+		{"db/schema.sql", true},
+		{"db/migrations/001.sql", false},
+		{"views/home.go", false},
+	}
+	for _, tt := range tests {
+		if got := c.matches(filepath.Join(root, tt.rel)); got != tt.want {
+			t.Errorf("matches(%q) = %v; want %v", tt.rel, got, tt.want)
+		}
+	}
+
+	if got := c.SupportedExtensions(); !slices.Equal(got, []string{".templ", ".sql"}) {
+		t.Errorf("SupportedExtensions() = %v; want [.templ .sql]", got)
+	}
+	c.Extensions = []string{".woff2"}
+	if got := c.SupportedExtensions(); !slices.Equal(got, []string{".woff2"}) {
+		t.Errorf("SupportedExtensions() = %v; want the configured [.woff2]", got)
+	}
+}
+
+func TestHandler_AllExtensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		patterns   []string
+		extensions []string
+		want       bool
+	}{
+		{"directory pattern", []string{"assets/fonts/"}, nil, true},
+		{"no patterns", nil, nil, true},
+		{"file without extension", []string{"*.templ", "Makefile"}, nil, true},
+		{"wildcard extension", []string{"static/*.*"}, nil, true},
+		{"extensions of patterns", []string{"*.templ", "db/*.sql"}, nil, false},
+		{"configured extensions", []string{"assets/fonts/"}, []string{".woff2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("cmd", t.TempDir(), "true")
+			c.Patterns, c.Extensions = tt.patterns, tt.extensions
+			if got := c.AllExtensions(); got != tt.want {
+				t.Errorf("AllExtensions() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_DirectoryPatternDispatch(t *testing.T) {
+	root := t.TempDir()
+	c, logs := newHandler(t, root, "sh", "-c", `echo "copy $0"`, "{file}")
+	c.Patterns = []string{"assets/fonts/"}
+
+	// the "copy fonts" use case: every file below the folder, whatever its extension
+	w := devwatch.New(&devwatch.WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []devwatch.FilesEventHandlers{c},
+		Logger:             func(message ...any) {},
+		ExitChan:           make(chan bool, 1),
+	})
+	existing := filepath.Join(root, "assets", "fonts", "old.woff2")
+	font := filepath.Join(root, "assets", "fonts", "inter.woff2")
+	other := filepath.Join(root, "assets", "img", "logo.png")
+	writeFiles := func(files ...string) {
+		for _, file := range files {
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeFiles(existing, other)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go w.FileWatcherStart(&wg)
+	defer func() {
+		w.ExitChan <- true
+		wg.Wait()
+	}()
+
+	// the files existing at startup don't run the command
+	time.Sleep(300 * time.Millisecond)
+	c.Wait()
+	if got := logs.Lines(); len(got) != 0 {
+		t.Fatalf("logged %q at startup; want no run", got)
+	}
+
+	writeFiles(font, other)
+	want := "cmd copy " + font
+	deadline := time.Now().Add(3 * time.Second)
+	for !slices.Contains(logs.Lines(), want) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.Wait()
+	if got := logs.Lines(); !slices.Equal(got, []string{want}) {
+		t.Errorf("logged %q; want only %q", got, want)
+	}
+}
+
+func TestHandler_EmptyCommand(t *testing.T) {
+	c := &Handler{RootDir: t.TempDir()}
+	if err := c.NewFileEvent("a.txt", ".txt", "a.txt", "write"); err == nil {
+		t.Error("NewFileEvent() = nil; want an error for an empty Command")
+	}
+}
+
+func TestHandler_Events(t *testing.T) {
+	c := New("cmd", t.TempDir(), "true")
+	if got := c.SupportedEvents(); !slices.Contains(got, "remove") || !slices.Contains(got, "write") {
+		t.Errorf("SupportedEvents() = %v; want every event by default", got)
+	}
+	c.Events = []string{"create", "write"}
+	if got := c.SupportedEvents(); !slices.Equal(got, []string{"create", "write"}) {
+		t.Errorf("SupportedEvents() = %v; want [create write]", got)
+	}
+}
+
+func TestHandler_RunsWithPlaceholders(t *testing.T) {
+	root := t.TempDir()
+	c, logs := newHandler(t, root, "sh", "-c", `echo "$0 $1 $2 $3 $GREETING"; pwd; echo oops >&2`, "{event}", "{ext}", "{dir}", "{file}")
+	c.Patterns = []string{"views/"}
+	c.Env = []string{"GREETING=hi"}
+	c.Dir = root
+
+	file := filepath.Join(root, "views", "home.templ")
+	if err := c.NewFileEvent("home.templ", ".templ", file, "write"); err != nil {
+		t.Fatal(err)
+	}
+	c.NewFileEvent("other.templ", ".templ", filepath.Join(root, "other.templ"), "write") // not matched
+	c.Wait()
+
+	want := []string{
+		fmt.Sprintf("cmd write .templ %s %s hi", filepath.Dir(file), file),
+		"cmd " + root,
+		"cmd oops",
+	}
+	got := logs.Lines()
+	if len(got) != len(want) {
+		t.Fatalf("logged %q; want %q", got, want)
+	}
+	for _, line := range want {
+		if !slices.Contains(got, line) {
+			t.Errorf("logged %q; missing %q", got, line)
+		}
+	}
+}
+
+func TestHandler_LogsFailure(t *testing.T) {
+	c, logs := newHandler(t, t.TempDir(), "sh", "-c", "exit 3")
+
+	c.NewFileEvent("a.txt", ".txt", "a.txt", "write")
+	c.Wait()
+
+	if got := logs.Lines(); len(got) != 1 || !strings.Contains(got[0], "failed: exit status 3") {
+		t.Errorf("logged %q; want the exit status", got)
+	}
+}
+
+func TestHandler_Debounce(t *testing.T) {
+	root := t.TempDir()
+	c, logs := newHandler(t, root, "echo", "generate", "{file}")
+	c.Debounce = 100 * time.Millisecond
+	a, b := filepath.Join(root, "a.sql"), filepath.Join(root, "b.sql")
+
+	for range 3 {
+		c.NewFileEvent("a.sql", ".sql", a, "write")
+	}
+	c.NewFileEvent("b.sql", ".sql", b, "write")
+	time.Sleep(300 * time.Millisecond)
+	c.Wait()
+
+	got := logs.Lines()
+	slices.Sort(got)
+	want := []string{"cmd generate " + a, "cmd generate " + b}
+	if !slices.Equal(got, want) {
+		t.Errorf("logged %q; want one run per file %q", got, want)
+	}
+}
+
+func TestHandler_CancelPrevious(t *testing.T) {
+	root := t.TempDir()
+	c, logs := newHandler(t, root, "sh", "-c", `if [ "$0" = slow ]; then sleep 30; fi; echo "$0 done"`, "{event}")
+	c.CancelPrevious = true
+	c.WaitDelay = 500 * time.Millisecond
+
+	c.NewFileEvent("a", "", filepath.Join(root, "a"), "slow")
+	time.Sleep(200 * time.Millisecond)
+	start := time.Now()
+	c.NewFileEvent("a", "", filepath.Join(root, "a"), "write")
+	c.Wait()
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("second run finished after %v; the slow run wasn't cancelled", elapsed)
+	}
+	if got := logs.Lines(); !slices.Equal(got, []string{"cmd cancelled", "cmd write done"}) {
+		t.Errorf("logged %q; want the cancelled run then the new one", got)
+	}
+}
+
+func TestHandler_RunsInOrderWithoutCancel(t *testing.T) {
+	root := t.TempDir()
+	c, logs := newHandler(t, root, "sh", "-c", `sleep 0.2; echo "$0"`, "{event}")
+
+	c.NewFileEvent("a", "", filepath.Join(root, "a"), "create")
+	c.NewFileEvent("a", "", filepath.Join(root, "a"), "write")
+	c.Wait()
+
+	if got := logs.Lines(); !slices.Equal(got, []string{"cmd create", "cmd write"}) {
+		t.Errorf("logged %q; want both runs in order", got)
+	}
+}
@@ -1,6 +1,8 @@
 package devwatch
 
 import (
+	"hash"
+	"hash/maphash"
 	"sync"
 	"time"
 
@@ -29,6 +31,68 @@ type WatchConfig struct {
 	FolderEvents       FolderEvent          // when directories are created/removed for architecture detection
 
 	BrowserReload func() error // when change frontend files reload browser
+	// BrowserReloadWithPayload receives what changed in the batch, preferred over BrowserReload when set
+	BrowserReloadWithPayload func(ReloadPayload) error
+	// ReloadTargets reload groups of clients separately eg: an admin UI and a public PWA
+	ReloadTargets []ReloadTarget
+
+	// Readiness gate: before each reload poll these until they answer or ReadyTimeout expires
+	ReadyAddr    string        // local TCP address eg: "localhost:8080"
+	ReadyURL     string        // HTTP health URL, any status below 400 counts as ready eg: "http://localhost:8080/health"
+	ReadyTimeout time.Duration // default: 10s
+
+	BlockingHandlers []string                     // handler names whose failure cancels the batch reload eg: ["wasm"]
+	ErrorOverlay     func([]HandlerFailure) error // shows failures of BlockingHandlers in the browser, called with nil to clear
+
+	// Reload lifecycle: AfterBuild runs once handlers succeeded in a batch, even when
+	// none asks for a reload or a blocking handler failed (eg: restart the server).
+	// BeforeReload runs before each reload (eg: flush the asset bundle). An error of
+	// either skips the reload. AfterReload receives the reload result (eg: record timing).
+	AfterBuild        func(ReloadPayload) error
+	BeforeReload      func(ReloadPayload) error
+	AfterReload       func(ReloadPayload, error)
+	MinReloadInterval time.Duration // reloads requested sooner are coalesced into one at the end of the interval
+
+	Timing          Timing            // debounce and reload windows. default: 50ms leading debounce, 50ms reload delay
+	ExtensionTiming map[string]Timing // per extension overrides eg: {".css": {ReloadDelay: 10 * time.Millisecond}}
+
+	// WatchedEvents lists the operations dispatched to handlers eg: "create", "write", "remove", "rename", "chmod".
+	// Empty means every operation except "chmod".
+	WatchedEvents []string
+
+	// BuildTestFiles also routes _test.go files to build handlers. By default
+	// they only reach handlers implementing TestEventHandler.
+	BuildTestFiles bool
+
+	// Generators run //go:generate directives when their inputs change, before
+	// the handlers see the event eg: {Inputs: ["db/*.sql"], Run: "sqlc"}
+	Generators []Generator
+
+	// SkipUnchangedWrites dispatches a write only when the file content differs from
+	// the content at its last successful dispatch, no matter how much time has passed.
+	// The last MaxTrackedFiles dispatched files are remembered.
+	SkipUnchangedWrites bool
+
+	// ExpectWriteWindow is how long writes announced through DevWatch.ExpectWrite
+	// or FileEventResult.Outputs are ignored. default: 2s
+	ExpectWriteWindow time.Duration
+
+	// LoopLimit is the number of consecutive events caused by files written in
+	// handler runs after which the feedback loop is broken: the last handler and
+	// the path are paused and OnEventLoop is called. default: 10, negative: disabled
+	LoopLimit   int
+	OnEventLoop func(EventLoop)
+
+	// GitOperationTimeout is the longest a git checkout, rebase or merge holds
+	// file events, eg: a merge stopped on conflicts or a stale index.lock.
+	// default: 1m, negative: git operations are not detected
+	GitOperationTimeout time.Duration
+
+	MaxHashSize int64            // files larger than this are compared by size and mtime only. 0: 8MB, negative: no limit
+	NewHash     func() hash.Hash // content hash used for change detection. default: hash/maphash
+
+	MaxTrackedFiles int           // per-file debounce entries kept in memory. default: 4096
+	TrackedFileTTL  time.Duration // entries untouched for this long are dropped. default: 30m
 
 	Logger          func(message ...any) // For logging output
 	ExitChan        chan bool            // global channel to signal the exit
@@ -37,13 +101,59 @@ type WatchConfig struct {
 
 type DevWatch struct {
 	*WatchConfig
-	watcher         *fsnotify.Watcher
-	depFinder       *depfind.GoDepFind // Dependency finder for Go projects
+	watcher       *fsnotify.Watcher
+	depFinder     *depfind.GoDepFind // Dependency finder for Go projects, rebuilt when the module layout changes
+	depMu         sync.Mutex
+	embeds        map[string]embedPackage // package dir => //go:embed patterns, nil until indexed
+	embedMu       sync.Mutex
+	directives    map[string][]GenerateDirective // package dir => //go:generate lines, nil until indexed
+	generatorRuns map[int]generatorRun           // last run of each Generators entry
+	generateMu    sync.Mutex
+	expected      map[string]expectedWrite // path => content written by a handler, see ExpectWrite
+	expectMu      sync.Mutex
+	// feedback loop detection: recent handler runs and the chain of the event being dispatched
+	handlerRuns    []handlerRun
+	running        bool     // a handler run is in progress
+	runWrites      []string // writes it announced through ExpectWrite
+	eventChain     []LoopStep
+	pausedPaths    map[string]EventLoop
+	pausedHandlers map[string]EventLoop
+	loopMu         sync.Mutex
+	// Pause / Resume: events held per path until the last Resume
+	pauses     int
+	holding    bool // events are held, from the first Pause until the flush
+	held       map[string]heldEvent
+	heldOrder  []string
+	pauseMu    sync.Mutex
+	resumeCh   chan struct{}
+	resumeOnce sync.Once
+	// Go build handler runs deferred to the end of a flush, see flushHeld
+	flushing    bool
+	flushBuilds []deferredBuild
+	// git operation in progress in gitDir, holding events with a Pause
+	gitDir     string
+	gitActive  bool // a state marker exists
+	gitHolding bool // the Pause is held, until the operation ends or times out
+	gitTimer   *time.Timer
+	gitMu      sync.Mutex
+	// files and folders excluded from watching, see Contain
 	no_add_to_watch map[string]bool
 	noAddMu         sync.RWMutex
-	// reload timer to debounce browser reloads across multiple events
-	reloadTimer *time.Timer
-	reloadMutex sync.Mutex
+	// reload targets, each debouncing browser reloads across multiple events
+	defaultReload *reloadTarget // BrowserReload, for changes matching no ReloadTarget
+	reloadTargets []*reloadTarget
+	reloadOnce    sync.Once
+	// per-file debounce state and content hashes keyed by file stamp (inode, mtime, size)
+	eventState      *lruCache[string, fileEventKey]
+	dispatchedState *lruCache[string, dispatchedFile] // bounded by MaxTrackedFiles only, see SkipUnchangedWrites
+	hashCache       *lruCache[fileStamp, [32]byte]
+	// cached AffectedMains / OwnersOf answers and the imports of the .go files,
+	// seeded by InitialRegistration and bounded by MaxTrackedFiles only
+	ownerCache  *lruCache[string, ownership]
+	importCache *lruCache[string, []string]
+	stateOnce   sync.Once
+	hashSeed    maphash.Seed
+	hashOnce    sync.Once
 	// logMu           sync.Mutex // No longer needed with Print func
 }
 
@@ -52,5 +162,25 @@ func New(c *WatchConfig) *DevWatch {
 		WatchConfig: c,
 		depFinder:   depfind.New(c.AppRootDir),
 	}
+	dw.initFileState()
 	return dw
 }
+
+// initFileState creates the bounded per-file state once
+func (h *DevWatch) initFileState() {
+	h.stateOnce.Do(func() {
+		maxFiles := h.MaxTrackedFiles
+		if maxFiles <= 0 {
+			maxFiles = defaultMaxTrackedFiles
+		}
+		ttl := h.TrackedFileTTL
+		if ttl <= 0 {
+			ttl = defaultTrackedFileTTL
+		}
+		h.eventState = newLRUCache[string, fileEventKey](maxFiles, ttl)
+		h.dispatchedState = newLRUCache[string, dispatchedFile](maxFiles, 0)
+		h.hashCache = newLRUCache[fileStamp, [32]byte](maxHashCacheEntries, 0)
+		h.ownerCache = newLRUCache[string, ownership](maxFiles, ttl)
+		h.importCache = newLRUCache[string, []string](maxFiles, 0)
+	})
+}
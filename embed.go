@@ -0,0 +1,131 @@
+package devwatch
+
+import (
+	"go/build"
+	"io/fs"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// embedPackage is the //go:embed usage of one package directory
+type embedPackage struct {
+	patterns []string // eg: "templates/*.html", "all:static"
+	goFile   string   // a .go file of the package, used for ownership queries
+}
+
+// embedIndex returns the packages that embed files, indexed once by walking AppRootDir
+func (h *DevWatch) embedIndex() map[string]embedPackage {
+	h.embedMu.Lock()
+	defer h.embedMu.Unlock()
+	if h.embeds != nil {
+		return maps.Clone(h.embeds)
+	}
+
+	h.embeds = make(map[string]embedPackage)
+	filepath.WalkDir(h.AppRootDir, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if dir != h.AppRootDir && h.Contain(dir) {
+			return filepath.SkipDir
+		}
+		if pkg, ok := readEmbeds(dir); ok {
+			h.embeds[dir] = pkg
+		}
+		return nil
+	})
+	return maps.Clone(h.embeds)
+}
+
+// resetEmbeds drops the index so it is rebuilt on the next query
+func (h *DevWatch) resetEmbeds() {
+	h.embedMu.Lock()
+	h.embeds = nil
+	h.embedMu.Unlock()
+}
+
+// refreshEmbeds re-reads the //go:embed directives of the package of a .go file
+func (h *DevWatch) refreshEmbeds(goFile string) {
+	h.embedMu.Lock()
+	defer h.embedMu.Unlock()
+	if h.embeds == nil {
+		return // not indexed yet
+	}
+	dir := filepath.Dir(goFile)
+	if pkg, ok := readEmbeds(dir); ok {
+		h.embeds[dir] = pkg
+	} else {
+		delete(h.embeds, dir)
+	}
+}
+
+// readEmbeds returns the //go:embed patterns of the package in dir
+func readEmbeds(dir string) (embedPackage, bool) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil || len(pkg.EmbedPatterns) == 0 || len(pkg.GoFiles) == 0 {
+		return embedPackage{}, false
+	}
+	return embedPackage{
+		patterns: pkg.EmbedPatterns,
+		goFile:   filepath.Join(dir, pkg.GoFiles[0]),
+	}, true
+}
+
+// embedOwners returns the Go handlers owning a package that embeds filePath
+func (h *DevWatch) embedOwners(filePath string) []FilesEventHandlers {
+	if !slices.ContainsFunc(h.FilesEventHandlers, func(handler FilesEventHandlers) bool {
+		return slices.Contains(handler.SupportedExtensions(), ".go")
+	}) {
+		return nil // nothing to route to, skip indexing
+	}
+
+	var ids []HandlerID
+	for dir, pkg := range h.embedIndex() {
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if slices.ContainsFunc(pkg.patterns, func(pattern string) bool {
+			return embedMatches(pattern, filepath.ToSlash(rel))
+		}) {
+			ids = append(ids, h.OwnersOf(pkg.goFile)...)
+		}
+	}
+
+	// keep the registration order of the handlers
+	var owners []FilesEventHandlers
+	for _, handler := range h.FilesEventHandlers {
+		if slices.Contains(ids, HandlerID(handlerName(handler))) {
+			owners = append(owners, handler)
+		}
+	}
+	return owners
+}
+
+// embedMatches reports whether a //go:embed pattern includes the file rel
+// (slash separated, relative to the package directory). A pattern matching a
+// directory includes its files except those starting with "." or "_",
+// unless the pattern has the "all:" prefix.
+func embedMatches(pattern, rel string) bool {
+	all := strings.HasPrefix(pattern, "all:")
+	pattern = strings.TrimPrefix(pattern, "all:")
+
+	for prefix := rel; prefix != "."; prefix = path.Dir(prefix) {
+		if ok, _ := path.Match(pattern, prefix); !ok {
+			continue
+		}
+		if prefix == rel || all {
+			return true
+		}
+		for _, part := range strings.Split(strings.TrimPrefix(rel, prefix+"/"), "/") {
+			if strings.HasPrefix(part, ".") || strings.HasPrefix(part, "_") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
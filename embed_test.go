@@ -0,0 +1,154 @@
+package devwatch
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// RecordingHandler records the files it received
+type RecordingHandler struct {
+	mu         sync.Mutex
+	Name_      string
+	MainInput  string
+	Extensions []string
+	files      []string
+}
+
+func (h *RecordingHandler) NewFileEvent(fileName, extension, filePath, event string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.files = append(h.files, fileName+":"+event)
+	return nil
+}
+func (h *RecordingHandler) Name() string                      { return h.Name_ }
+func (h *RecordingHandler) SupportedExtensions() []string     { return h.Extensions }
+func (h *RecordingHandler) MainInputFileRelativePath() string { return h.MainInput }
+func (h *RecordingHandler) UnobservedFiles() []string         { return nil }
+
+func (h *RecordingHandler) Files() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.files...)
+}
+
+func TestEmbedMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"templates/*.html", "templates/index.html", true},
+		{"templates/*.html", "templates/index.css", false},
+		{"templates/*.html", "templates/admin/index.html", false},
+		{"static", "static/css/app.css", true},
+		{"static", "static/.hidden/app.css", false},
+		{"static", "static/_draft.css", false},
+		{"all:static", "static/_draft.css", true},
+		{"schema.sql", "schema.sql", true},
+		{"schema.sql", "other.sql", false},
+	}
+	for _, tt := range tests {
+		if got := embedMatches(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("embedMatches(%q, %q) = %v; want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func newEmbedProject(t *testing.T) (root string, server, assets *RecordingHandler) {
+	t.Helper()
+	root = t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod": "module example\n\ngo 1.22\n",
+		"cmd/server/main.go": "package main\n\nimport \"embed\"\n\n" +
+			"//go:embed templates/*.html\nvar templates embed.FS\n\n" +
+			"//go:embed schema.sql\nvar schema string\n\nfunc main() {}\n",
+		"cmd/server/templates/index.html": "<h1>hi</h1>",
+		"cmd/server/schema.sql":           "CREATE TABLE t (id int);",
+		"web/page.html":                   "<p>not embedded</p>",
+	})
+	server = &RecordingHandler{Name_: "server", MainInput: "cmd/server/main.go", Extensions: []string{".go"}}
+	assets = &RecordingHandler{Name_: "assets", Extensions: []string{".html"}}
+	return root, server, assets
+}
+
+func TestHandleFileEvent_EmbeddedAssetReachesGoHandler(t *testing.T) {
+	root, server, assets := newEmbedProject(t)
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, assets},
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	w.handleFileEvent("index.html", filepath.Join(root, "cmd/server/templates/index.html"), OpWrite, false)
+	w.handleFileEvent("schema.sql", filepath.Join(root, "cmd/server/schema.sql"), OpWrite, false)
+	w.handleFileEvent("page.html", filepath.Join(root, "web/page.html"), OpWrite, false)
+
+	if got := server.Files(); len(got) != 2 || got[0] != "index.html:write" || got[1] != "schema.sql:write" {
+		t.Errorf("server received %v; want the two embedded files", got)
+	}
+	if got := assets.Files(); len(got) != 2 {
+		t.Errorf("assets received %v; want both .html files", got)
+	}
+}
+
+func TestHandleFileEvent_EmbedDirectiveAdded(t *testing.T) {
+	root, server, assets := newEmbedProject(t)
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, assets},
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	page := filepath.Join(root, "web/page.html")
+	w.handleFileEvent("page.html", page, OpWrite, false)
+
+	// web gets its own package embedding page.html
+	writeModule(t, root, map[string]string{
+		"web/web.go": "package web\n\nimport _ \"embed\"\n\n//go:embed page.html\nvar Page string\n",
+		"cmd/server/main.go": "package main\n\nimport (\n\t\"embed\"\n\n\t\"example/web\"\n)\n\n" +
+			"//go:embed templates/*.html\nvar templates embed.FS\n\nfunc main() { _ = web.Page }\n",
+	})
+	w.handleFileEvent("web.go", filepath.Join(root, "web/web.go"), OpCreate, false)
+	w.handleFileEvent("main.go", filepath.Join(root, "cmd/server/main.go"), OpWrite, false)
+	server.mu.Lock()
+	server.files = nil
+	server.mu.Unlock()
+
+	w.handleFileEvent("page.html", page, OpWrite, false)
+	if got := server.Files(); len(got) != 1 || got[0] != "page.html:write" {
+		t.Errorf("server received %v; want page.html once it is embedded", got)
+	}
+}
+
+func TestInitialRegistration_EmbeddedAssets(t *testing.T) {
+	root, server, assets := newEmbedProject(t)
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, assets},
+		Logger:             func(message ...any) {},
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	w.watcher = watcher
+
+	w.InitialRegistration()
+
+	want := map[string]bool{"main.go:create": true, "index.html:create": true, "schema.sql:create": true}
+	got := server.Files()
+	if len(got) != len(want) {
+		t.Fatalf("server received %v; want %v", got, want)
+	}
+	for _, f := range got {
+		if !want[f] {
+			t.Errorf("server received unexpected %s", f)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package devwatch
+
+import (
+	"slices"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Event names passed to handlers in the event argument of NewFileEvent.
+const (
+	OpCreate = "create"
+	OpWrite  = "write"
+	OpRemove = "remove"
+	OpRename = "rename"
+	OpChmod  = "chmod"
+)
+
+// defaultEvents are dispatched when WatchConfig.WatchedEvents is empty.
+// chmod is left out: touch, git and permission changes emit it without
+// changing file content.
+var defaultEvents = []string{OpCreate, OpWrite, OpRemove, OpRename}
+
+// EventsFilter is optionally implemented by a FilesEventHandlers to receive
+// only some event operations. Handlers that don't implement it receive every
+// operation allowed by WatchConfig.WatchedEvents. The files existing at startup
+// still reach it as "create" events, see HandlerInitialFiles to skip them.
+type EventsFilter interface {
+	SupportedEvents() []string // eg: ["create","write"]
+}
+
+// HandlerAllExtensions is optionally implemented by handlers selecting files
+// by path rather than by extension, eg: a command run for every file of a
+// directory. When AllExtensions reports true every file is dispatched to the
+// handler whatever SupportedExtensions returns; .go files still need AllGoFiles.
+type HandlerAllExtensions interface {
+	AllExtensions() bool
+}
+
+// handlesExtension reports whether files with extension are dispatched to handler
+func handlesExtension(handler FilesEventHandlers, extension string) bool {
+	if a, ok := handler.(HandlerAllExtensions); ok && a.AllExtensions() {
+		return true
+	}
+	return slices.Contains(handler.SupportedExtensions(), extension)
+}
+
+// HandlerInitialFiles is optionally implemented by handlers that must not
+// receive the files existing at startup, eg: a command that would run once per
+// file of the tree. When InitialFiles reports false InitialRegistration sends
+// them no "create" events; later changes are dispatched as usual.
+type HandlerInitialFiles interface {
+	InitialFiles() bool
+}
+
+// initialFiles reports whether InitialRegistration sends existing files to handler
+func initialFiles(handler FilesEventHandlers) bool {
+	i, ok := handler.(HandlerInitialFiles)
+	return !ok || i.InitialFiles()
+}
+
+// opName reduces an fsnotify op to a single event name.
+// When several ops are combined the most significant one wins.
+func opName(op fsnotify.Op) string {
+	switch {
+	case op.Has(fsnotify.Remove):
+		return OpRemove
+	case op.Has(fsnotify.Rename):
+		return OpRename
+	case op.Has(fsnotify.Create):
+		return OpCreate
+	case op.Has(fsnotify.Write):
+		return OpWrite
+	case op.Has(fsnotify.Chmod):
+		return OpChmod
+	}
+	return ""
+}
+
+// eventAllowed reports whether the event passes the global WatchedEvents filter.
+func (h *DevWatch) eventAllowed(event string) bool {
+	if event == "" {
+		return false
+	}
+	if len(h.WatchedEvents) == 0 {
+		return slices.Contains(defaultEvents, event)
+	}
+	return slices.Contains(h.WatchedEvents, event)
+}
+
+// handlerAcceptsEvent reports whether the handler wants to receive the event.
+func handlerAcceptsEvent(handler FilesEventHandlers, event string) bool {
+	if f, ok := handler.(EventsFilter); ok {
+		return slices.Contains(f.SupportedEvents(), event)
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package devwatch
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FilteredFileEvent tracks events and declares the ops it supports
+type FilteredFileEvent struct {
+	TrackingFileEvent
+	Events []string
+}
+
+func (f *FilteredFileEvent) SupportedEvents() []string {
+	return f.Events
+}
+
+func TestOpName(t *testing.T) {
+	tests := []struct {
+		op   fsnotify.Op
+		want string
+	}{
+		{fsnotify.Create, OpCreate},
+		{fsnotify.Write, OpWrite},
+		{fsnotify.Remove, OpRemove},
+		{fsnotify.Rename, OpRename},
+		{fsnotify.Chmod, OpChmod},
+		{fsnotify.Create | fsnotify.Write, OpCreate},
+		{fsnotify.Write | fsnotify.Chmod, OpWrite},
+		{fsnotify.Remove | fsnotify.Chmod, OpRemove},
+		{0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := opName(tt.op); got != tt.want {
+			t.Errorf("opName(%v) = %q; want %q", tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestWatchEvents_OpsFilter(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := tempDir + "/style.css"
+	if err := os.WriteFile(cssFile, []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var allCalled, createOnlyCalled int32
+	allTracker := &EventTracker{}
+	createOnlyTracker := &EventTracker{}
+
+	allHandler := &TrackingFileEvent{
+		Tracker:              allTracker,
+		Called:               &allCalled,
+		SupportedExtensions_: []string{".css"},
+	}
+	createOnlyHandler := &FilteredFileEvent{
+		TrackingFileEvent: TrackingFileEvent{
+			Tracker:              createOnlyTracker,
+			Called:               &createOnlyCalled,
+			SupportedExtensions_: []string{".css"},
+		},
+		Events: []string{OpCreate},
+	}
+
+	var reloadCount int64
+	config := &WatchConfig{
+		AppRootDir:         tempDir,
+		FilesEventHandlers: []FilesEventHandlers{allHandler, createOnlyHandler},
+		BrowserReload: func() error {
+			atomic.AddInt64(&reloadCount, 1)
+			return nil
+		},
+		Logger:   func(message ...any) { t.Log(message...) },
+		ExitChan: make(chan bool, 1),
+	}
+
+	w := New(config)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+
+	// chmod never reaches handlers with the default filter
+	watcher.Events <- fsnotify.Event{Name: cssFile, Op: fsnotify.Chmod}
+	time.Sleep(100 * time.Millisecond)
+
+	if got := allTracker.GetEvents(); len(got) != 0 {
+		t.Errorf("chmod should be filtered, handler received: %v", got)
+	}
+	if atomic.LoadInt64(&reloadCount) != 0 {
+		t.Error("chmod should not trigger a browser reload")
+	}
+
+	// write reaches only the handler that accepts it
+	watcher.Events <- fsnotify.Event{Name: cssFile, Op: fsnotify.Write}
+	time.Sleep(100 * time.Millisecond)
+
+	w.ExitChan <- true
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchEvents did not exit in time")
+	}
+
+	if got := allTracker.GetEvents(); len(got) != 1 || got[0] != "write:style.css" {
+		t.Errorf("expected [write:style.css], got %v", got)
+	}
+	if got := createOnlyTracker.GetEvents(); len(got) != 0 {
+		t.Errorf("create-only handler should not receive write, got %v", got)
+	}
+}
+
+func TestWatchedEvents_IncludesChmod(t *testing.T) {
+	w := New(&WatchConfig{
+		AppRootDir:    t.TempDir(),
+		WatchedEvents: []string{OpWrite, OpChmod},
+		Logger:        func(message ...any) { t.Log(message...) },
+	})
+
+	if !w.eventAllowed(OpChmod) {
+		t.Error("chmod should be allowed when listed in WatchedEvents")
+	}
+	if w.eventAllowed(OpCreate) {
+		t.Error("create should be filtered when not listed in WatchedEvents")
+	}
+}
+
+func TestInitialRegistration_IgnoresEventsFilter(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(tempDir+"/style.css", []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a write-only handler still learns about the files existing at startup
+	var called int32
+	tracker := &EventTracker{}
+	writeOnly := &FilteredFileEvent{
+		TrackingFileEvent: TrackingFileEvent{Tracker: tracker, Called: &called, SupportedExtensions_: []string{".css"}},
+		Events:            []string{OpWrite},
+	}
+	w := New(&WatchConfig{
+		AppRootDir:         tempDir,
+		FilesEventHandlers: []FilesEventHandlers{writeOnly},
+		Logger:             func(message ...any) {},
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	w.watcher = watcher
+
+	w.InitialRegistration()
+	if got := tracker.GetEvents(); len(got) != 1 || got[0] != "create:style.css" {
+		t.Errorf("expected [create:style.css], got %v", got)
+	}
+}
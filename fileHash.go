@@ -0,0 +1,122 @@
+package devwatch
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/maphash"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// defaultMaxHashSize is used when WatchConfig.MaxHashSize is zero.
+	defaultMaxHashSize = 8 << 20 // 8MB
+	// maxHashCacheEntries bounds the stamp → hash cache.
+	maxHashCacheEntries = 4096
+	// racyStampWindow: files modified more recently than this are not cached,
+	// a second write within the same mtime tick could keep size and mtime unchanged.
+	racyStampWindow = 100 * time.Millisecond
+)
+
+// fileStamp identifies a file version by its metadata
+type fileStamp struct {
+	ino     uint64
+	size    int64
+	modTime int64
+	path    string // only set when the platform has no inode numbers
+}
+
+func newFileStamp(path string, info os.FileInfo) fileStamp {
+	stamp := fileStamp{
+		ino:     fileInode(info),
+		size:    info.Size(),
+		modTime: info.ModTime().UnixNano(),
+	}
+	if stamp.ino == 0 {
+		stamp.path = path
+	}
+	return stamp
+}
+
+// hash returns a metadata-only hash, used for files above the hashing size cap
+func (s fileStamp) hash() [32]byte {
+	var sum [32]byte
+	binary.LittleEndian.PutUint64(sum[0:], s.ino)
+	binary.LittleEndian.PutUint64(sum[8:], uint64(s.size))
+	binary.LittleEndian.PutUint64(sum[16:], uint64(s.modTime))
+	sum[24] = 1 // never equal to the zero hash of a missing file
+	return sum
+}
+
+// maxHashSize returns the size above which content hashing is skipped, 0 means no limit
+func (h *DevWatch) maxHashSize() int64 {
+	switch {
+	case h.MaxHashSize < 0:
+		return 0
+	case h.MaxHashSize == 0:
+		return defaultMaxHashSize
+	}
+	return h.MaxHashSize
+}
+
+// newHasher returns the configured content hash or a seeded maphash by default
+func (h *DevWatch) newHasher() hash.Hash {
+	if h.NewHash != nil {
+		return h.NewHash()
+	}
+	h.hashOnce.Do(func() {
+		h.hashSeed = maphash.MakeSeed()
+	})
+	hasher := &maphash.Hash{}
+	hasher.SetSeed(h.hashSeed)
+	return hasher
+}
+
+// fileSnapshot returns the metadata stamp and content hash of filePath for smart debouncing.
+// Returns zero values if the file cannot be read (will be treated as different).
+// Hashes are cached by stamp so an unchanged file is never read twice, and files
+// above the size cap are identified by their metadata only.
+func (h *DevWatch) fileSnapshot(filePath string) (fileStamp, [32]byte) {
+	var zeroHash [32]byte
+
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		return fileStamp{}, zeroHash // File doesn't exist or can't be read
+	}
+	stamp := newFileStamp(filePath, info)
+
+	if limit := h.maxHashSize(); limit > 0 && info.Size() > limit {
+		return stamp, stamp.hash()
+	}
+
+	h.initFileState()
+	if cached, ok := h.hashCache.Get(stamp); ok {
+		return stamp, cached
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return stamp, zeroHash
+	}
+	defer file.Close()
+
+	hasher := h.newHasher()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return stamp, zeroHash // Error reading file
+	}
+
+	var sum [32]byte
+	copy(sum[:], hasher.Sum(nil))
+
+	if time.Since(info.ModTime()) > racyStampWindow {
+		h.hashCache.Put(stamp, sum)
+	}
+	return stamp, sum
+}
+
+// calculateFileHash returns the content hash of filePath for smart debouncing
+func (h *DevWatch) calculateFileHash(filePath string) [32]byte {
+	_, sum := h.fileSnapshot(filePath)
+	return sum
+}
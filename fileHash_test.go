@@ -0,0 +1,138 @@
+package devwatch
+
+import (
+	"hash"
+	"hash/fnv"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingHash returns a NewHash func that counts how many hashers were created
+func countingHash(count *int32) func() hash.Hash {
+	return func() hash.Hash {
+		atomic.AddInt32(count, 1)
+		return fnv.New64a()
+	}
+}
+
+// writeOldFile writes content and moves its mtime out of the racy window
+func writeOldFile(t *testing.T, path, content string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-age)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileSnapshot_CachesByStamp(t *testing.T) {
+	file := t.TempDir() + "/app.wasm"
+	writeOldFile(t, file, "wasm binary", time.Hour)
+
+	var hashers int32
+	w := New(&WatchConfig{NewHash: countingHash(&hashers)})
+
+	stamp1, sum1 := w.fileSnapshot(file)
+	stamp2, sum2 := w.fileSnapshot(file)
+
+	if stamp1 != stamp2 || sum1 != sum2 {
+		t.Fatal("unchanged file should return the same stamp and hash")
+	}
+	if got := atomic.LoadInt32(&hashers); got != 1 {
+		t.Errorf("file hashed %d times; want 1 (second call served from cache)", got)
+	}
+
+	// new content and mtime produce a new stamp and a fresh hash
+	writeOldFile(t, file, "wasm binary v2", time.Minute)
+	stamp3, sum3 := w.fileSnapshot(file)
+	if stamp3 == stamp1 || sum3 == sum1 {
+		t.Error("modified file should produce a different stamp and hash")
+	}
+	if got := atomic.LoadInt32(&hashers); got != 2 {
+		t.Errorf("file hashed %d times; want 2", got)
+	}
+}
+
+func TestFileSnapshot_RecentFilesNotCached(t *testing.T) {
+	file := t.TempDir() + "/style.css"
+	if err := os.WriteFile(file, []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var hashers int32
+	w := New(&WatchConfig{NewHash: countingHash(&hashers)})
+
+	w.fileSnapshot(file)
+	w.fileSnapshot(file)
+
+	if got := atomic.LoadInt32(&hashers); got != 2 {
+		t.Errorf("file hashed %d times; want 2 (just-written files are not cached)", got)
+	}
+}
+
+func TestFileSnapshot_SizeCap(t *testing.T) {
+	file := t.TempDir() + "/image.png"
+	writeOldFile(t, file, "0123456789", time.Hour)
+
+	var hashers int32
+	w := New(&WatchConfig{
+		NewHash:     countingHash(&hashers),
+		MaxHashSize: 4,
+	})
+
+	_, sum1 := w.fileSnapshot(file)
+	if got := atomic.LoadInt32(&hashers); got != 0 {
+		t.Fatalf("files above MaxHashSize should not be hashed, hashed %d times", got)
+	}
+
+	// same size, different mtime: detected through metadata only
+	writeOldFile(t, file, "9876543210", time.Minute)
+	_, sum2 := w.fileSnapshot(file)
+	if sum1 == sum2 {
+		t.Error("metadata hash should change with mtime")
+	}
+	var zero [32]byte
+	if sum1 == zero || sum2 == zero {
+		t.Error("metadata hash should never equal the missing file hash")
+	}
+}
+
+func TestMaxHashSize(t *testing.T) {
+	tests := []struct {
+		config int64
+		want   int64
+	}{
+		{0, defaultMaxHashSize},
+		{-1, 0},
+		{1024, 1024},
+	}
+	for _, tt := range tests {
+		w := &DevWatch{WatchConfig: &WatchConfig{MaxHashSize: tt.config}}
+		if got := w.maxHashSize(); got != tt.want {
+			t.Errorf("MaxHashSize %d: maxHashSize() = %d; want %d", tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateFileHash_DefaultHasher(t *testing.T) {
+	dir := t.TempDir()
+	fileA := dir + "/a.css"
+	fileB := dir + "/b.css"
+	writeOldFile(t, fileA, "same content", time.Hour)
+	writeOldFile(t, fileB, "same content", time.Hour)
+
+	// DevWatch built without New must still hash with a usable seed
+	w := &DevWatch{WatchConfig: &WatchConfig{}}
+	if w.calculateFileHash(fileA) != w.calculateFileHash(fileB) {
+		t.Error("identical content should produce identical hashes")
+	}
+
+	var zero [32]byte
+	if w.calculateFileHash(dir+"/missing.css") != zero {
+		t.Error("missing file should return the zero hash")
+	}
+}
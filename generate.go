@@ -0,0 +1,226 @@
+package devwatch
+
+import (
+	"bufio"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// generatedSlack absorbs the coarse clock the kernel uses for mtimes, which
+// can stamp a write made by a generator slightly before its run started
+const generatedSlack = 20 * time.Millisecond
+
+// Generator runs //go:generate directives when one of its inputs changes,
+// before the handlers see the event, so the Go handlers build the fresh output.
+type Generator struct {
+	// Inputs relative to AppRootDir: a directory matches every file below it
+	// eg: "db/queries", anything else is a path.Match pattern eg: "db/*.sql"
+	Inputs  []string
+	Run     string // regexp matched against the whole directive line, as "go generate -run" eg: "sqlc". default: every directive
+	Package string // package directory relative to AppRootDir eg: "db". default: the packages with a matching directive
+}
+
+// GenerateDirective is a //go:generate line of the module.
+type GenerateDirective struct {
+	File    string // .go file eg: "/app/db/db.go"
+	Line    int
+	Command string // eg: "sqlc generate"
+
+	text string // the whole line, which "go generate -run" matches
+}
+
+// generateTarget is the build context of "go generate": the host platform
+// with the "generate" tag, files it excludes are not scanned
+var generateTarget = BuildTarget{Tags: []string{"generate"}}
+
+// generatorRun is the time window of the last run of a generator. Inputs
+// written inside it come from the generator itself and don't trigger it again.
+type generatorRun struct {
+	start, end time.Time
+}
+
+// GenerateDirectives returns the //go:generate directives of the module,
+// indexed once by walking AppRootDir and refreshed when a .go file changes.
+func (h *DevWatch) GenerateDirectives() []GenerateDirective {
+	index := h.generateIndex()
+	var all []GenerateDirective
+	for _, dir := range slices.Sorted(maps.Keys(index)) {
+		all = append(all, index[dir]...)
+	}
+	return all
+}
+
+// generateIndex returns the directives by package directory
+func (h *DevWatch) generateIndex() map[string][]GenerateDirective {
+	h.generateMu.Lock()
+	defer h.generateMu.Unlock()
+	if h.directives != nil {
+		return maps.Clone(h.directives)
+	}
+
+	h.directives = make(map[string][]GenerateDirective)
+	filepath.WalkDir(h.AppRootDir, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if dir != h.AppRootDir && h.Contain(dir) {
+			return filepath.SkipDir
+		}
+		if directives := readDirectives(dir); len(directives) > 0 {
+			h.directives[dir] = directives
+		}
+		return nil
+	})
+	return maps.Clone(h.directives)
+}
+
+// resetDirectives drops the index so it is rebuilt on the next query
+func (h *DevWatch) resetDirectives() {
+	h.generateMu.Lock()
+	h.directives = nil
+	h.generateMu.Unlock()
+}
+
+// refreshDirectives re-reads the //go:generate directives of the package of a .go file
+func (h *DevWatch) refreshDirectives(goFile string) {
+	h.generateMu.Lock()
+	defer h.generateMu.Unlock()
+	if h.directives == nil {
+		return // not indexed yet
+	}
+	dir := filepath.Dir(goFile)
+	if directives := readDirectives(dir); len(directives) > 0 {
+		h.directives[dir] = directives
+	} else {
+		delete(h.directives, dir)
+	}
+}
+
+// readDirectives returns the //go:generate lines of the .go files in dir that
+// "go generate" scans, _test.go files included
+func readDirectives(dir string) []GenerateDirective {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var directives []GenerateDirective
+	for _, file := range files {
+		if !targetMatches(generateTarget, file) {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if command, ok := strings.CutPrefix(scanner.Text(), "//go:generate "); ok {
+				directives = append(directives, GenerateDirective{File: file, Line: line, Command: strings.TrimSpace(command), text: text})
+			}
+		}
+		f.Close()
+	}
+	return directives
+}
+
+// runGenerators runs the generators whose inputs include filePath, except
+// those that wrote it themselves
+func (h *DevWatch) runGenerators(filePath string) {
+	if len(h.Generators) == 0 {
+		return
+	}
+	rel, err := filepath.Rel(h.AppRootDir, filePath)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+
+	for i, g := range h.Generators {
+		if !pathMatches(g.Inputs, rel) || h.generatedBy(i, filePath) {
+			continue
+		}
+		pkgs, err := h.generatePackages(g)
+		if err != nil {
+			h.Logger("go generate:", err)
+			continue
+		}
+		if len(pkgs) == 0 {
+			h.Logger("go generate: no //go:generate directive matches", g.Run)
+			continue
+		}
+
+		args := []string{"generate"}
+		if g.Run != "" {
+			args = append(args, "-run", g.Run)
+		}
+		cmd := exec.Command("go", append(args, pkgs...)...)
+		cmd.Dir = h.AppRootDir
+
+		start := time.Now()
+		out, err := cmd.CombinedOutput()
+		h.generateMu.Lock()
+		if h.generatorRuns == nil {
+			h.generatorRuns = make(map[int]generatorRun)
+		}
+		h.generatorRuns[i] = generatorRun{start: start, end: time.Now()}
+		h.generateMu.Unlock()
+
+		if err != nil {
+			h.Logger("go generate", strings.Join(pkgs, " "), "failed:", err, strings.TrimSpace(string(out)))
+		} else if len(out) > 0 {
+			h.Logger("go generate", strings.Join(pkgs, " ")+":", strings.TrimSpace(string(out)))
+		}
+	}
+}
+
+// generatedBy reports whether the event comes from the last run of generator
+// i: the file was written during the run, or removed right after it
+func (h *DevWatch) generatedBy(i int, filePath string) bool {
+	h.generateMu.Lock()
+	run, ok := h.generatorRuns[i]
+	h.generateMu.Unlock()
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return time.Since(run.end) < time.Second
+	}
+	mtime := info.ModTime()
+	return !mtime.Before(run.start.Add(-generatedSlack)) && !mtime.After(run.end)
+}
+
+// generatePackages returns the packages a generator runs in, in "go generate" form
+func (h *DevWatch) generatePackages(g Generator) ([]string, error) {
+	if g.Package != "" {
+		return []string{"./" + filepath.ToSlash(filepath.Clean(g.Package))}, nil
+	}
+	var re *regexp.Regexp
+	if g.Run != "" {
+		var err error
+		if re, err = regexp.Compile(g.Run); err != nil {
+			return nil, err
+		}
+	}
+
+	var pkgs []string
+	for dir, directives := range h.generateIndex() {
+		if !slices.ContainsFunc(directives, func(d GenerateDirective) bool {
+			return re == nil || re.MatchString(d.text)
+		}) {
+			continue
+		}
+		pkg := "."
+		if rel, err := filepath.Rel(h.AppRootDir, dir); err == nil && rel != "." {
+			pkg = "./" + filepath.ToSlash(rel)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	slices.Sort(pkgs)
+	return pkgs, nil
+}
@@ -0,0 +1,184 @@
+package devwatch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// GeneratedChecker records whether the generated file existed when it was called
+type GeneratedChecker struct {
+	RecordingHandler
+	generated string
+	seen      []bool
+}
+
+func (h *GeneratedChecker) NewFileEvent(fileName, extension, filePath, event string) error {
+	_, err := os.Stat(h.generated)
+	h.seen = append(h.seen, err == nil)
+	return h.RecordingHandler.NewFileEvent(fileName, extension, filePath, event)
+}
+
+// newGenerateProject writes a package whose first directive logs each run to
+// runs.log, copies schema.sql to copy.sql and writes schema_gen.go
+func newGenerateProject(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod":        "module example\n\ngo 1.22\n",
+		"db/schema.sql": "CREATE TABLE users (id INT);\n",
+		"db/db.go": "package db\n\n" +
+			"//go:generate sh -c \"echo run >> runs.log && cp schema.sql copy.sql && echo 'package db' > schema_gen.go\"\n" +
+			"//go:generate echo other\n",
+		"api/api.go": "package api\n",
+	})
+	return root
+}
+
+func runsLogged(t *testing.T, root string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, "db/runs.log"))
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run\n")
+}
+
+func TestGenerateDirectives(t *testing.T) {
+	root := newGenerateProject(t)
+	w := New(&WatchConfig{AppRootDir: root, Logger: func(message ...any) {}})
+
+	got := w.GenerateDirectives()
+	if len(got) != 2 {
+		t.Fatalf("directives = %+v; want 2", got)
+	}
+	if got[0].File != filepath.Join(root, "db/db.go") || got[0].Line != 3 || !strings.HasPrefix(got[0].Command, "sh -c") {
+		t.Errorf("directive = %+v; want the sh line of db/db.go", got[0])
+	}
+	if got[1].Line != 4 || got[1].Command != "echo other" {
+		t.Errorf("directive = %+v; want line 4 \"echo other\"", got[1])
+	}
+
+	// a directive added to a .go file is picked up on its event
+	apiFile := filepath.Join(root, "api/api.go")
+	if err := os.WriteFile(apiFile, []byte("package api\n\n//go:generate echo api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.handleFileEvent("api.go", apiFile, OpWrite, false)
+	if got := w.GenerateDirectives(); len(got) != 3 || got[0].Command != "echo api" {
+		t.Errorf("directives = %+v; want the api directive indexed", got)
+	}
+}
+
+func TestGenerateDirectives_BuildConstraints(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod":                "module example\n\ngo 1.22\n",
+		"db/db.go":              "package db\n",
+		"db/ignored.go":         "//go:build ignore\n\npackage db\n\n//go:generate echo ignored\n",
+		"db/gen.go":             "//go:build generate\n\npackage db\n\n//go:generate echo generate tag\n",
+		"db/db_test.go":         "package db\n\n//go:generate echo test\n",
+		"db/other_plan9_386.go": "package db\n\n//go:generate echo other platform\n",
+	})
+	w := New(&WatchConfig{AppRootDir: root, Logger: func(message ...any) {}})
+
+	// the files "go generate" scans, _test.go ones included
+	var got []string
+	for _, d := range w.GenerateDirectives() {
+		got = append(got, d.Command)
+	}
+	if want := []string{"echo test", "echo generate tag"}; !slices.Equal(got, want) {
+		t.Errorf("directives = %q; want %q", got, want)
+	}
+}
+
+func TestHandleFileEvent_RunsGeneratorBeforeHandlers(t *testing.T) {
+	root := newGenerateProject(t)
+	sql := &GeneratedChecker{
+		RecordingHandler: RecordingHandler{Name_: "sql", Extensions: []string{".sql"}},
+		generated:        filepath.Join(root, "db/schema_gen.go"),
+	}
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{sql},
+		Generators:         []Generator{{Inputs: []string{"db/*.sql"}, Run: "runs.log"}},
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	w.handleFileEvent("schema.sql", filepath.Join(root, "db/schema.sql"), OpWrite, false)
+	if n := runsLogged(t, root); n != 1 {
+		t.Fatalf("generator ran %d times; want 1", n)
+	}
+	if len(sql.seen) != 1 || !sql.seen[0] {
+		t.Errorf("handler saw the generated file = %v; want it written before the handler ran", sql.seen)
+	}
+
+	// copy.sql matches the inputs but was written by the generator itself
+	copyFile := filepath.Join(root, "db/copy.sql")
+	w.handleFileEvent("copy.sql", copyFile, OpWrite, false)
+	if n := runsLogged(t, root); n != 1 {
+		t.Errorf("generator ran %d times; its own output must not trigger it", n)
+	}
+	if got := sql.Files(); len(got) != 2 {
+		t.Errorf("handler received %v; the generated file still reaches the handlers", got)
+	}
+
+	// a later edit of the same file is genuine
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(copyFile, []byte("-- edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.handleFileEvent("copy.sql", copyFile, OpWrite, false)
+	if n := runsLogged(t, root); n != 2 {
+		t.Errorf("generator ran %d times; want a run for the user edit", n)
+	}
+
+	// files outside the inputs never run it
+	w.handleFileEvent("api.go", filepath.Join(root, "api/api.go"), OpWrite, false)
+	if n := runsLogged(t, root); n != 2 {
+		t.Errorf("generator ran %d times; want none for other files", n)
+	}
+}
+
+func TestGeneratePackages(t *testing.T) {
+	root := newGenerateProject(t)
+	w := New(&WatchConfig{AppRootDir: root, Logger: func(message ...any) {}})
+
+	tests := []struct {
+		name string
+		gen  Generator
+		want string
+	}{
+		{"matching directive", Generator{Run: "echo other"}, "./db"},
+		{"no match", Generator{Run: "sqlc"}, ""},
+		{"explicit package", Generator{Run: "sqlc", Package: "api/"}, "./api"},
+		// like "go generate -run", the whole line is matched
+		{"anchored directive", Generator{Run: "^//go:generate echo other$"}, "./db"},
+		{"anchored command", Generator{Run: "^echo other"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.generatePackages(tt.gen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("generatePackages() = %v; want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := w.generatePackages(Generator{Run: "("}); err == nil {
+		t.Error("invalid Run regexp should fail")
+	}
+}
@@ -0,0 +1,155 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultGitOperationTimeout = time.Minute
+
+// gitMarkers exist in the git dir while an operation rewrites the work tree
+var gitMarkers = []string{
+	"index.lock",       // checkout, reset, pull, stash...
+	"rebase-merge",     // interactive rebase
+	"rebase-apply",     // rebase, am
+	"MERGE_HEAD",       // merge
+	"CHERRY_PICK_HEAD", // cherry-pick
+	"REVERT_HEAD",      // revert
+}
+
+// watchGitDir watches the state markers of the repository containing AppRootDir,
+// even when .git is unobserved, and holds events if an operation is in progress
+func (h *DevWatch) watchGitDir() {
+	if h.GitOperationTimeout < 0 {
+		return
+	}
+	gitDir := findGitDir(h.AppRootDir)
+	if gitDir == "" {
+		return
+	}
+	if err := h.watcher.Add(gitDir); err != nil {
+		h.Logger("Failed to watch git dir:", gitDir, err)
+		return
+	}
+	h.gitMu.Lock()
+	h.gitDir = gitDir
+	h.gitMu.Unlock()
+	h.checkGitOperation()
+}
+
+// findGitDir returns the git dir of the repository containing root, following
+// the "gitdir:" file of worktrees and submodules, or "" outside a repository
+func findGitDir(root string) string {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return ""
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dotGit
+			}
+			data, err := os.ReadFile(dotGit)
+			if err != nil {
+				return ""
+			}
+			target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+			if !ok {
+				return ""
+			}
+			target = strings.TrimSpace(target)
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			return filepath.Clean(target)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// gitEvent reports whether the event belongs to the git dir, in which case
+// it only updates the git operation state
+func (h *DevWatch) gitEvent(eventName string) bool {
+	h.gitMu.Lock()
+	gitDir := h.gitDir
+	h.gitMu.Unlock()
+	if gitDir == "" || filepath.Dir(eventName) != gitDir {
+		return false
+	}
+	h.checkGitOperation()
+	return true
+}
+
+// gitOperationInProgress reports whether any state marker exists in gitDir
+func gitOperationInProgress(gitDir string) bool {
+	for _, marker := range gitMarkers {
+		if _, err := os.Stat(filepath.Join(gitDir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGitOperation pauses dispatch when a git operation starts and resumes it
+// when the operation ends, so its files are dispatched as one batch
+func (h *DevWatch) checkGitOperation() {
+	h.gitMu.Lock()
+	active := gitOperationInProgress(h.gitDir)
+	if active == h.gitActive {
+		h.gitMu.Unlock()
+		return
+	}
+	h.gitActive = active
+
+	if active {
+		timeout := h.GitOperationTimeout
+		if timeout == 0 {
+			timeout = defaultGitOperationTimeout
+		}
+		h.gitHolding = true
+		h.gitTimer = time.AfterFunc(timeout, func() { h.gitOperationTimedOut(timeout) })
+		h.gitMu.Unlock()
+		h.Pause()
+		return
+	}
+
+	h.gitTimer.Stop()
+	holding := h.gitHolding
+	h.gitHolding = false
+	h.gitMu.Unlock()
+	if holding {
+		h.Resume()
+	}
+}
+
+// gitOperationTimedOut resumes dispatch when an operation is still in progress
+// after GitOperationTimeout, eg: a merge stopped on conflicts or a stale index.lock
+func (h *DevWatch) gitOperationTimedOut(timeout time.Duration) {
+	h.gitMu.Lock()
+	if !h.gitHolding {
+		h.gitMu.Unlock()
+		return
+	}
+	h.gitHolding = false
+	h.gitMu.Unlock()
+	h.Logger("git operation still in progress after", timeout, "- dispatching held events")
+	h.Resume()
+}
+
+// stopGitTimer cancels the GitOperationTimeout of the operation in progress, so
+// it does not log nor resume after the watcher stopped
+func (h *DevWatch) stopGitTimer() {
+	h.gitMu.Lock()
+	defer h.gitMu.Unlock()
+	if h.gitTimer != nil {
+		h.gitTimer.Stop()
+	}
+	h.gitHolding = false
+}
@@ -0,0 +1,223 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// gitMarker creates or removes a state marker and sends its event
+func gitMarker(t *testing.T, watcher *fsnotify.Watcher, path string, create bool) {
+	t.Helper()
+	op := fsnotify.Remove
+	if create {
+		op = fsnotify.Create
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	} else if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Events <- fsnotify.Event{Name: path, Op: op}
+}
+
+func newGitDevWatch(t *testing.T, root string, timeout time.Duration) (*DevWatch, *fsnotify.Watcher, *RecordingHandler) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	handler := &RecordingHandler{Name_: "txt", Extensions: []string{".txt"}}
+	w := New(&WatchConfig{
+		AppRootDir:          root,
+		FilesEventHandlers:  []FilesEventHandlers{handler},
+		GitOperationTimeout: timeout,
+		Logger:              func(message ...any) { t.Log(message...) },
+		ExitChan:            make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+	w.watchGitDir()
+	return w, watcher, handler
+}
+
+func TestFindGitDir(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"repo/.git/HEAD":      "ref: refs/heads/main\n",
+		"repo/web/index.html": "",
+		"worktree/.git":       "gitdir: ../repo/.git/worktrees/wt\n",
+		"plain/file.txt":      "",
+	})
+
+	tests := []struct {
+		dir, want string
+	}{
+		{"repo", "repo/.git"},
+		{"repo/web", "repo/.git"},
+		{"worktree", "repo/.git/worktrees/wt"},
+	}
+	for _, tt := range tests {
+		if got := findGitDir(filepath.Join(root, tt.dir)); got != filepath.Join(root, tt.want) {
+			t.Errorf("findGitDir(%s) = %q; want %q", tt.dir, got, tt.want)
+		}
+	}
+	if got := findGitDir(filepath.Join(root, "plain")); strings.HasPrefix(got, root) {
+		t.Errorf("findGitDir(plain) = %q; want no repository inside the temp dir", got)
+	}
+}
+
+func TestGitOperation_HoldsEventsUntilDone(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"a.txt": "a", "b.txt": "b"})
+	a, b := filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")
+	gitDir := filepath.Join(root, ".git")
+
+	w, watcher, handler := newGitDevWatch(t, root, 0)
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+
+	// a rebase rewrites the tree in waves, each taking index.lock
+	gitMarker(t, watcher, filepath.Join(gitDir, "rebase-merge", "done"), true)
+	watcher.Events <- fsnotify.Event{Name: filepath.Join(gitDir, "rebase-merge"), Op: fsnotify.Create}
+	for i, content := range []string{"a1", "a2"} {
+		gitMarker(t, watcher, filepath.Join(gitDir, "index.lock"), true)
+		writeModule(t, root, map[string]string{"a.txt": content, "b.txt": content})
+		watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Write}
+		watcher.Events <- fsnotify.Event{Name: b, Op: fsnotify.Write}
+		gitMarker(t, watcher, filepath.Join(gitDir, "index.lock"), false)
+		time.Sleep(resumeSettle + 50*time.Millisecond)
+		if got := handler.Files(); len(got) != 0 {
+			t.Fatalf("wave %d: handler received %v during the rebase", i, got)
+		}
+	}
+	if !w.Paused() {
+		t.Fatal("Paused() = false during the rebase")
+	}
+
+	gitMarker(t, watcher, filepath.Join(gitDir, "rebase-merge"), false)
+	time.Sleep(resumeSettle + 150*time.Millisecond)
+	want := []string{"a.txt:write", "b.txt:write"}
+	if got := handler.Files(); !slices.Equal(got, want) {
+		t.Errorf("handler received %v; want %v once the rebase ended", got, want)
+	}
+
+	// other files of the git dir never reach the handlers
+	writeModule(t, root, map[string]string{".git/ORIG_HEAD.txt": "x"})
+	watcher.Events <- fsnotify.Event{Name: filepath.Join(gitDir, "ORIG_HEAD.txt"), Op: fsnotify.Create}
+	time.Sleep(100 * time.Millisecond)
+	if got := handler.Files(); len(got) != 2 {
+		t.Errorf("handler received %v; git dir files are not dispatched", got)
+	}
+}
+
+func TestGitOperation_PendingTrailingEvent(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"a.txt": "a"})
+	a := filepath.Join(root, "a.txt")
+	gitDir := filepath.Join(root, ".git")
+
+	w, watcher, handler := newGitDevWatch(t, root, 0)
+	w.ExtensionTiming = map[string]Timing{".txt": {Debounce: 80 * time.Millisecond, Mode: DebounceTrailing}}
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+
+	// an edit debounced just before a checkout takes index.lock
+	writeModule(t, root, map[string]string{"a.txt": "edit"})
+	watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Write}
+	time.Sleep(20 * time.Millisecond)
+	gitMarker(t, watcher, filepath.Join(gitDir, "index.lock"), true)
+
+	time.Sleep(150 * time.Millisecond)
+	if got := handler.Files(); len(got) != 0 {
+		t.Fatalf("handler received %v during the checkout", got)
+	}
+
+	gitMarker(t, watcher, filepath.Join(gitDir, "index.lock"), false)
+	time.Sleep(resumeSettle + 100*time.Millisecond)
+	if got := handler.Files(); !slices.Equal(got, []string{"a.txt:write"}) {
+		t.Errorf("handler received %v; want the pending write once the checkout ended", got)
+	}
+}
+
+func TestGitOperation_Timeout(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"a.txt": "a"})
+	a := filepath.Join(root, "a.txt")
+
+	w, watcher, handler := newGitDevWatch(t, root, 150*time.Millisecond)
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+
+	// a merge stopped on conflicts: the user resolves them while MERGE_HEAD exists
+	gitMarker(t, watcher, filepath.Join(root, ".git", "MERGE_HEAD"), true)
+	writeModule(t, root, map[string]string{"a.txt": "merged"})
+	watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Write}
+	time.Sleep(50 * time.Millisecond)
+	if got := handler.Files(); len(got) != 0 {
+		t.Fatalf("handler received %v during the merge", got)
+	}
+
+	time.Sleep(150*time.Millisecond + resumeSettle + 100*time.Millisecond)
+	if got := handler.Files(); !slices.Equal(got, []string{"a.txt:write"}) {
+		t.Fatalf("handler received %v; want the held write after GitOperationTimeout", got)
+	}
+
+	// later edits are dispatched while the operation is still in progress
+	time.Sleep(10 * time.Millisecond)
+	writeModule(t, root, map[string]string{"a.txt": "resolved"})
+	watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Write}
+	time.Sleep(150 * time.Millisecond)
+	if got := handler.Files(); len(got) != 2 {
+		t.Errorf("handler received %v; want edits dispatched after the timeout", got)
+	}
+}
+
+func TestGitOperation_AlreadyInProgress(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{".git/MERGE_HEAD": "abc"})
+	w, watcher, _ := newGitDevWatch(t, root, 0)
+	defer watcher.Close()
+	defer w.stopGitTimer()
+	if !w.Paused() {
+		t.Error("Paused() = false with a merge in progress at start")
+	}
+
+	disabled, watcher2, _ := newGitDevWatch(t, t.TempDir(), -1)
+	defer watcher2.Close()
+	writeModule(t, disabled.AppRootDir, map[string]string{".git/MERGE_HEAD": "abc"})
+	if disabled.gitEvent(filepath.Join(disabled.AppRootDir, ".git", "MERGE_HEAD")) || disabled.Paused() {
+		t.Error("negative GitOperationTimeout should not detect git operations")
+	}
+}
+
+func TestGitOperation_TimeoutStoppedOnExit(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{".git/MERGE_HEAD": "abc"})
+	w, _, _ := newGitDevWatch(t, root, 100*time.Millisecond)
+	stop := runWatchLoop(t, w)
+	stop()
+
+	time.Sleep(200 * time.Millisecond)
+	w.pauseMu.Lock()
+	defer w.pauseMu.Unlock()
+	if w.pauses != 1 {
+		t.Error("GitOperationTimeout resumed dispatch after the watcher stopped")
+	}
+}
@@ -0,0 +1,131 @@
+package gotest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of one package in a test run.
+type Result struct {
+	Package string        // import path eg: "example/pkg/greet"
+	Passed  bool          // false when a test or the build failed
+	NoTests bool          // the package has no test files
+	Failed  []string      // failed tests eg: ["TestGreet", "TestGreet/empty"]
+	Output  string        // output of the failed tests or the build errors
+	Elapsed time.Duration // package run time
+}
+
+// testEvent is one line of "go test -json" output, see "go doc test2json"
+type testEvent struct {
+	Action      string
+	Package     string
+	ImportPath  string // build-output and build-fail events
+	FailedBuild string // import path whose build output explains a package failure
+	Test        string
+	Output      string
+	Elapsed     float64
+}
+
+// packageRun accumulates the events of one package
+type packageRun struct {
+	result      Result
+	done        bool
+	failedBuild string              // see testEvent.FailedBuild
+	output      []string            // package level output
+	testOutput  map[string][]string // output per test
+}
+
+// parseEvents reads "go test -json" output and returns one Result per package
+// in the order the packages were first seen. Lines that are not JSON, eg:
+// build errors printed by older toolchains, are kept as orphan output.
+func parseEvents(r io.Reader) (results []Result, orphan string) {
+	runs := map[string]*packageRun{}
+	var order []string
+	var orphans []string
+	builds := map[string][]string{} // build output per import path
+	get := func(pkg string) *packageRun {
+		run, ok := runs[pkg]
+		if !ok {
+			run = &packageRun{result: Result{Package: pkg}, testOutput: map[string][]string{}}
+			runs[pkg] = run
+			order = append(order, pkg)
+		}
+		return run
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev testEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &ev) != nil {
+			orphans = append(orphans, scanner.Text()+"\n")
+			continue
+		}
+
+		switch ev.Action {
+		case "build-output":
+			builds[ev.ImportPath] = append(builds[ev.ImportPath], ev.Output)
+			continue
+		case "build-fail":
+			continue // reported by the package fail event
+		}
+		if ev.Package == "" {
+			continue
+		}
+
+		run := get(ev.Package)
+		switch {
+		case ev.Action == "output" && ev.Test != "":
+			run.testOutput[ev.Test] = append(run.testOutput[ev.Test], ev.Output)
+		case ev.Action == "output":
+			run.output = append(run.output, ev.Output)
+			if strings.Contains(ev.Output, "[no test files]") {
+				run.result.NoTests = true
+			}
+		case ev.Action == "fail" && ev.Test != "":
+			run.result.Failed = append(run.result.Failed, ev.Test)
+		case ev.Action == "pass" && ev.Test == "", ev.Action == "skip" && ev.Test == "":
+			run.result.Passed, run.done = true, true
+			run.result.Elapsed = time.Duration(ev.Elapsed * float64(time.Second))
+		case ev.Action == "fail":
+			run.result.Passed, run.done = false, true
+			run.failedBuild = ev.FailedBuild
+			run.result.Elapsed = time.Duration(ev.Elapsed * float64(time.Second))
+		}
+	}
+
+	for _, pkg := range order {
+		run := runs[pkg]
+		if !run.done {
+			continue
+		}
+		if !run.result.Passed {
+			var out []string
+			for _, test := range run.result.Failed {
+				out = append(out, run.testOutput[test]...)
+			}
+			if len(out) == 0 {
+				out = append(builds[run.failedBuild], run.output...)
+			}
+			run.result.Output = strings.Join(out, "")
+		}
+		results = append(results, run.result)
+	}
+	return results, strings.Join(orphans, "")
+}
+
+// failedPackages returns the packages of results that failed
+func failedPackages(results []Result) []string {
+	var failed []string
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, r.Package)
+		}
+	}
+	return slices.Clip(failed)
+}
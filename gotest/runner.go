@@ -0,0 +1,264 @@
+// Package gotest provides a devwatch handler that runs the tests of the
+// packages affected by a change.
+//
+// A change to a .go file runs "go test" on its package and on every package
+// importing it, a change to a _test.go file only on its own package. A new
+// change cancels the run in progress:
+//
+//	tests := gotest.New(rootDir)
+//	tests.Logger = logger
+//	tests.Failures = srv.ShowErrors
+//
+//	cfg := &devwatch.WatchConfig{
+//		FilesEventHandlers: []devwatch.FilesEventHandlers{server, wasm, tests},
+//	}
+package gotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinywasm/depfind"
+	"github.com/tinywasm/devwatch"
+)
+
+// DefaultWaitDelay is how long a cancelled run may take to stop after the
+// interrupt before it is killed.
+const DefaultWaitDelay = 3 * time.Second
+
+// Runner is a devwatch handler running "go test" on the affected packages.
+// Runs are asynchronous so the watcher is never blocked by slow tests.
+type Runner struct {
+	RootDir   string                                // module root where "go test" runs
+	Args      []string                              // extra "go test" flags eg: ["-race", "-count=1"]
+	GoBin     string                                // go command. default: "go"
+	WaitDelay time.Duration                         // see DefaultWaitDelay
+	Logger    func(message ...any)                  // run summaries and failed test output
+	Failures  func([]devwatch.HandlerFailure) error // eg: reload.Server.ShowErrors, called with nil once tests pass again
+	OnResults func([]Result)                        // optional, receives the results of each completed run
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	failing bool
+	deps    *depfind.GoDepFind
+}
+
+// New returns a Runner testing the module at rootDir.
+func New(rootDir string) *Runner {
+	return &Runner{RootDir: rootDir}
+}
+
+func (r *Runner) Name() string                        { return "tests" }
+func (r *Runner) MainInputFileRelativePath() string   { return "" }
+func (r *Runner) SupportedExtensions() []string       { return []string{".go"} }
+func (r *Runner) UnobservedFiles() []string           { return nil }
+func (r *Runner) AllGoFiles() bool                    { return true }
+func (r *Runner) ReloadPolicy() devwatch.ReloadPolicy { return devwatch.ReloadNever }
+
+// NewFileEvent tests the package of filePath and the packages importing it.
+func (r *Runner) NewFileEvent(fileName, extension, filePath, event string) error {
+	if extension != ".go" {
+		return nil
+	}
+	r.start(func() ([]string, error) { return r.affected(filePath) })
+	return nil
+}
+
+// NewTestEvent tests only the package of the changed test file.
+func (r *Runner) NewTestEvent(filePath, packagePath, event string) error {
+	r.start(func() ([]string, error) { return []string{packagePath}, nil })
+	return nil
+}
+
+// Wait blocks until the current run, if any, has finished.
+func (r *Runner) Wait() {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Stop cancels the current run and waits for it to exit.
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+	r.Wait()
+}
+
+// affected returns the import paths of the package of filePath and of every
+// package of the module importing it
+func (r *Runner) affected(filePath string) ([]string, error) {
+	rel, err := filepath.Rel(r.RootDir, filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	target := "./" + filepath.ToSlash(rel)
+	if rel == "." {
+		target = "."
+	}
+
+	r.mu.Lock()
+	if r.deps == nil {
+		r.deps = depfind.New(r.RootDir)
+	}
+	deps := r.deps
+	r.mu.Unlock()
+
+	pkgs, err := deps.FindReverseDeps("./...", []string{target})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(pkgs)
+	return pkgs, nil
+}
+
+// start cancels the run in progress and starts a new one once it has exited.
+// packages is resolved in the run so the watcher doesn't wait for "go list".
+func (r *Runner) start(packages func() ([]string, error)) {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	prev := r.done
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	r.cancel, r.done = cancel, done
+	r.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		if prev != nil {
+			<-prev
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		pkgs, err := packages()
+		if err != nil {
+			r.log("Tests", "packages:", err)
+			return
+		}
+		if len(pkgs) == 0 || ctx.Err() != nil {
+			return
+		}
+		results, err := r.run(ctx, pkgs)
+		if ctx.Err() != nil {
+			return // superseded by a newer change, its results are obsolete
+		}
+		r.report(pkgs, results, err)
+	}()
+}
+
+// run executes "go test -json" on pkgs and parses its output
+func (r *Runner) run(ctx context.Context, pkgs []string) ([]Result, error) {
+	goBin := r.GoBin
+	if goBin == "" {
+		goBin = "go"
+	}
+	args := append([]string{"test", "-json"}, r.Args...)
+	args = append(args, pkgs...)
+
+	cmd := exec.CommandContext(ctx, goBin, args...)
+	cmd.Dir = r.RootDir
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = r.WaitDelay
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = DefaultWaitDelay
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	r.log("Tests", "running", strings.Join(pkgs, " "))
+	err := cmd.Run()
+
+	results, orphan := parseEvents(&stdout)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		err = nil // failures are reported by the results
+		if len(failedPackages(results)) == 0 {
+			// eg: invalid flags or a package that doesn't exist
+			err = errors.New(strings.TrimSpace(orphan + stderr.String()))
+		}
+	}
+	return results, err
+}
+
+// report logs the results and forwards the failures
+func (r *Runner) report(pkgs []string, results []Result, runErr error) {
+	var failures []devwatch.HandlerFailure
+	if runErr != nil {
+		r.log("Tests", "error:", runErr)
+		failures = append(failures, devwatch.HandlerFailure{
+			Handler: r.Name(),
+			Path:    strings.Join(pkgs, " "),
+			Error:   runErr.Error(),
+		})
+	}
+	for _, res := range results {
+		switch {
+		case res.NoTests:
+		case res.Passed:
+			r.log("Tests", "ok", res.Package, res.Elapsed.Round(time.Millisecond))
+		default:
+			msg := "build failed"
+			if len(res.Failed) > 0 {
+				msg = strings.Join(res.Failed, ", ")
+			}
+			r.log("Tests", "FAIL", res.Package, msg)
+			if res.Output != "" {
+				r.log(strings.TrimRight(res.Output, "\n"))
+			}
+			failures = append(failures, devwatch.HandlerFailure{
+				Handler: r.Name(),
+				Path:    res.Package,
+				Error:   fmt.Sprintf("%s: %s\n%s", res.Package, msg, res.Output),
+			})
+		}
+	}
+
+	if r.OnResults != nil {
+		r.OnResults(results)
+	}
+
+	r.mu.Lock()
+	wasFailing := r.failing
+	r.failing = len(failures) > 0
+	r.mu.Unlock()
+
+	if r.Failures == nil || (len(failures) == 0 && !wasFailing) {
+		return
+	}
+	if len(failures) == 0 {
+		failures = nil // tests pass again, clear the overlay
+	}
+	if err := r.Failures(failures); err != nil {
+		r.log("Tests", "failures:", err)
+	}
+}
+
+func (r *Runner) log(message ...any) {
+	if r.Logger != nil {
+		r.Logger(message...)
+	}
+}
@@ -0,0 +1,216 @@
+package gotest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/devwatch"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newProject writes a module where pkg/app imports pkg/greet and pkg/other is unrelated
+func newProject(t *testing.T, greetTest string) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                  "module example\n\ngo 1.22\n",
+		"pkg/greet/greet.go":      "package greet\n\nfunc Hi() string { return \"hi\" }\n",
+		"pkg/greet/greet_test.go": greetTest,
+		"pkg/app/app.go":          "package app\n\nimport \"example/pkg/greet\"\n\nfunc Run() string { return greet.Hi() }\n",
+		"pkg/app/app_test.go":     "package app\n\nimport \"testing\"\n\nfunc TestRun(t *testing.T) {}\n",
+		"pkg/other/other.go":      "package other\n",
+		"pkg/other/other_test.go": "package other\n\nimport \"testing\"\n\nfunc TestOther(t *testing.T) {}\n",
+	})
+	return root
+}
+
+const passingTest = "package greet\n\nimport \"testing\"\n\nfunc TestHi(t *testing.T) {}\n"
+
+// recorder collects the results and failures reported by a Runner
+type recorder struct {
+	mu       sync.Mutex
+	results  [][]Result
+	failures [][]devwatch.HandlerFailure
+}
+
+func (rec *recorder) attach(t *testing.T, r *Runner) {
+	r.Logger = func(message ...any) { t.Log(message...) }
+	r.OnResults = func(results []Result) {
+		rec.mu.Lock()
+		rec.results = append(rec.results, results)
+		rec.mu.Unlock()
+	}
+	r.Failures = func(failures []devwatch.HandlerFailure) error {
+		rec.mu.Lock()
+		rec.failures = append(rec.failures, failures)
+		rec.mu.Unlock()
+		return nil
+	}
+}
+
+func packages(results []Result) []string {
+	var pkgs []string
+	for _, r := range results {
+		pkgs = append(pkgs, r.Package)
+	}
+	return pkgs
+}
+
+func TestRunner_TestsAffectedPackages(t *testing.T) {
+	root := newProject(t, passingTest)
+	r := New(root)
+	rec := &recorder{}
+	rec.attach(t, r)
+
+	if err := r.NewFileEvent("greet.go", ".go", filepath.Join(root, "pkg/greet/greet.go"), "write"); err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	if len(rec.results) != 1 {
+		t.Fatalf("runs = %d; want 1", len(rec.results))
+	}
+	got := strings.Join(packages(rec.results[0]), " ")
+	if got != "example/pkg/app example/pkg/greet" {
+		t.Errorf("tested %q; want the package and its importer only", got)
+	}
+	for _, res := range rec.results[0] {
+		if !res.Passed {
+			t.Errorf("%s failed: %s", res.Package, res.Output)
+		}
+	}
+	if len(rec.failures) != 0 {
+		t.Errorf("failures = %v; want none while passing", rec.failures)
+	}
+}
+
+func TestRunner_TestFileRunsItsPackage(t *testing.T) {
+	root := newProject(t, passingTest)
+	r := New(root)
+	rec := &recorder{}
+	rec.attach(t, r)
+
+	r.NewTestEvent(filepath.Join(root, "pkg/other/other_test.go"), "./pkg/other", "write")
+	r.Wait()
+
+	if len(rec.results) != 1 || strings.Join(packages(rec.results[0]), " ") != "example/pkg/other" {
+		t.Errorf("results = %v; want only example/pkg/other", rec.results)
+	}
+}
+
+func TestRunner_ReportsFailuresAndClears(t *testing.T) {
+	root := newProject(t, "package greet\n\nimport \"testing\"\n\nfunc TestHi(t *testing.T) { t.Fatal(\"boom\") }\n")
+	r := New(root)
+	rec := &recorder{}
+	rec.attach(t, r)
+	testFile := filepath.Join(root, "pkg/greet/greet_test.go")
+
+	r.NewTestEvent(testFile, "./pkg/greet", "write")
+	r.Wait()
+
+	if len(rec.failures) != 1 || len(rec.failures[0]) != 1 {
+		t.Fatalf("failures = %v; want one failed package", rec.failures)
+	}
+	f := rec.failures[0][0]
+	if f.Handler != "tests" || f.Path != "example/pkg/greet" || !strings.Contains(f.Error, "TestHi") || !strings.Contains(f.Error, "boom") {
+		t.Errorf("failure = %+v; want TestHi with its output", f)
+	}
+
+	writeFiles(t, root, map[string]string{"pkg/greet/greet_test.go": passingTest})
+	r.NewTestEvent(testFile, "./pkg/greet", "write")
+	r.Wait()
+
+	if len(rec.failures) != 2 || rec.failures[1] != nil {
+		t.Errorf("failures = %v; want a nil report clearing the overlay", rec.failures)
+	}
+}
+
+func TestRunner_BuildFailure(t *testing.T) {
+	root := newProject(t, passingTest)
+	writeFiles(t, root, map[string]string{"pkg/greet/greet.go": "package greet\n\nfunc Hi() string { return 1 }\n"})
+	r := New(root)
+	rec := &recorder{}
+	rec.attach(t, r)
+
+	r.NewTestEvent(filepath.Join(root, "pkg/greet/greet_test.go"), "./pkg/greet", "write")
+	r.Wait()
+
+	if len(rec.failures) != 1 || len(rec.failures[0]) == 0 {
+		t.Fatalf("failures = %v; want the build failure", rec.failures)
+	}
+	if !strings.Contains(rec.failures[0][0].Error, "greet.go") {
+		t.Errorf("failure = %q; want the compiler output", rec.failures[0][0].Error)
+	}
+}
+
+func TestRunner_NewChangeCancelsRun(t *testing.T) {
+	root := newProject(t, "package greet\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\nfunc TestSlow(t *testing.T) { time.Sleep(time.Minute) }\n")
+	r := New(root)
+	r.WaitDelay = 500 * time.Millisecond
+	rec := &recorder{}
+	rec.attach(t, r)
+
+	r.NewTestEvent(filepath.Join(root, "pkg/greet/greet_test.go"), "./pkg/greet", "write")
+	time.Sleep(500 * time.Millisecond) // let the slow run start
+
+	start := time.Now()
+	r.NewTestEvent(filepath.Join(root, "pkg/other/other_test.go"), "./pkg/other", "write")
+	r.Wait()
+
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("second run finished after %v; the slow run wasn't cancelled", elapsed)
+	}
+	if len(rec.results) != 1 || strings.Join(packages(rec.results[0]), " ") != "example/pkg/other" {
+		t.Errorf("results = %v; want only the latest run reported", rec.results)
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	out := strings.Join([]string{
+		`{"Action":"start","Package":"example/a"}`,
+		`{"Action":"run","Package":"example/a","Test":"TestX"}`,
+		`{"Action":"output","Package":"example/a","Test":"TestX","Output":"    a_test.go:5: boom\n"}`,
+		`{"Action":"fail","Package":"example/a","Test":"TestX","Elapsed":0}`,
+		`{"Action":"fail","Package":"example/a","Elapsed":0.5}`,
+		`{"Action":"output","Package":"example/b","Output":"?   \texample/b\t[no test files]\n"}`,
+		`{"Action":"skip","Package":"example/b","Elapsed":0}`,
+		`{"ImportPath":"example/c [example/c.test]","Action":"build-output","Output":"c.go:3:1: syntax error\n"}`,
+		`{"ImportPath":"example/c [example/c.test]","Action":"build-fail"}`,
+		`{"Action":"fail","Package":"example/c","Elapsed":0,"FailedBuild":"example/c [example/c.test]"}`,
+		`# not json`,
+	}, "\n")
+
+	results, orphan := parseEvents(strings.NewReader(out))
+
+	if len(results) != 3 {
+		t.Fatalf("results = %+v; want 3 packages", results)
+	}
+	if a := results[0]; a.Passed || len(a.Failed) != 1 || !strings.Contains(a.Output, "boom") || a.Elapsed != 500*time.Millisecond {
+		t.Errorf("example/a = %+v; want TestX failed with its output", a)
+	}
+	if b := results[1]; !b.Passed || !b.NoTests {
+		t.Errorf("example/b = %+v; want passed without tests", b)
+	}
+	if c := results[2]; c.Passed || !strings.Contains(c.Output, "syntax error") {
+		t.Errorf("example/c = %+v; want the build output", c)
+	}
+	if orphan != "# not json\n" {
+		t.Errorf("orphan = %q", orphan)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package devwatch
+
+import "os"
+
+// fileInode returns 0, inode numbers are not available on this platform
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,53 @@
+// Package linelog forwards the output of child processes to a devwatch logger.
+package linelog
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Writer sends each complete line written to it to Log, preceded by Prefix.
+type Writer struct {
+	Log    func(message ...any)
+	Prefix string // eg: "server"
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// New returns a Writer logging to log with prefix.
+func New(log func(message ...any), prefix string) *Writer {
+	return &Writer{Log: log, Prefix: prefix}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := slices.Index(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush logs the last line when it has no trailing newline.
+func (w *Writer) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.log(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *Writer) log(line string) {
+	if w.Log != nil {
+		w.Log(w.Prefix, line)
+	}
+}
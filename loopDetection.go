@@ -0,0 +1,219 @@
+package devwatch
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLoopLimit = 10
+	maxHandlerRuns   = 64 // handler runs kept to find the cause of the next events
+)
+
+// LoopStep is one link of a feedback loop: a handler ran for a changed path.
+type LoopStep struct {
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+// EventLoop reports a feedback loop broken by DevWatch: files written by
+// handlers kept triggering handlers. The handler and the path are paused until ResumeLoops.
+type EventLoop struct {
+	Handler string     `json:"handler"` // paused handler, the one that wrote Path
+	Path    string     `json:"path"`    // paused path
+	Cycle   []LoopStep `json:"cycle"`   // the repeating steps, or the whole chain when no step repeats
+}
+
+// String describes the cycle eg: "main.go -[wasm]-> main.wasm -[assets]-> main.go"
+func (l EventLoop) String() string {
+	var b strings.Builder
+	for _, step := range l.Cycle {
+		b.WriteString(step.Path + " -[" + step.Handler + "]-> ")
+	}
+	b.WriteString(l.Path)
+	return b.String()
+}
+
+// handlerRun is the time window of a handler execution. Files written inside
+// it may have been written by the handler and then extend the chain of the
+// triggering event. A run that announced its writes (ExpectWrite or
+// FileEventResult.Outputs) only caused those. Otherwise a file only counts as
+// its output when it also changed during the previous run of the handler, as
+// a build output does on every run, so user saves landing in slow runs, even
+// alternating between files, are not mistaken for handler writes. A run never
+// causes its own trigger, eg: the user saving the file being built.
+type handlerRun struct {
+	start, end time.Time
+	handler    string
+	trigger    string
+	writes     []string   // paths announced during the run
+	changed    []string   // paths whose events found them modified during the run
+	chain      []LoopStep // steps that led to this run, including it
+}
+
+// loopChain returns the chain of handler runs that caused an event: empty for
+// user edits, removals and files written outside of any handler run
+func (h *DevWatch) loopChain(path string, info os.FileInfo) []LoopStep {
+	if info == nil {
+		return nil
+	}
+	mtime := info.ModTime()
+	h.loopMu.Lock()
+	defer h.loopMu.Unlock()
+	for i := len(h.handlerRuns) - 1; i >= 0; i-- {
+		run := &h.handlerRuns[i]
+		if mtime.Before(run.start.Add(-generatedSlack)) || mtime.After(run.end) {
+			continue
+		}
+		if !slices.Contains(run.changed, path) {
+			run.changed = append(run.changed, path)
+		}
+		if h.causedBy(i, path) {
+			return run.chain
+		}
+	}
+	return nil
+}
+
+// causedBy reports whether a change of path was written by the run at index i
+// of handlerRuns, see handlerRun
+func (h *DevWatch) causedBy(i int, path string) bool {
+	run := h.handlerRuns[i]
+	if len(run.writes) > 0 {
+		return slices.Contains(run.writes, path)
+	}
+	if path == run.trigger {
+		return false
+	}
+	for j := i - 1; j >= 0; j-- {
+		if prev := h.handlerRuns[j]; prev.handler == run.handler {
+			return slices.Contains(prev.changed, path) || slices.Contains(prev.writes, path)
+		}
+	}
+	return false
+}
+
+// loopBroken reports whether the event belongs to a feedback loop: its path is
+// paused, or the chain of handler runs that caused it reached LoopLimit and
+// already went through the path, in which case the last handler and the path
+// are paused and the loop reported. Otherwise the chain is kept for the
+// handler runs of this event.
+func (h *DevWatch) loopBroken(eventName string, info os.FileInfo) bool {
+	h.loopMu.Lock()
+	_, paused := h.pausedPaths[eventName]
+	h.loopMu.Unlock()
+	if paused {
+		return true
+	}
+	if h.LoopLimit < 0 {
+		return false
+	}
+
+	chain := h.loopChain(eventName, info)
+	limit := h.LoopLimit
+	if limit == 0 {
+		limit = defaultLoopLimit
+	}
+	if len(chain) < limit || !slices.ContainsFunc(chain, func(s LoopStep) bool { return s.Path == eventName }) {
+		h.loopMu.Lock()
+		h.eventChain = chain
+		h.loopMu.Unlock()
+		return false
+	}
+
+	last := chain[len(chain)-1]
+	loop := EventLoop{Handler: last.Handler, Path: eventName, Cycle: loopCycle(chain, eventName)}
+	h.loopMu.Lock()
+	if h.pausedPaths == nil {
+		h.pausedPaths = make(map[string]EventLoop)
+		h.pausedHandlers = make(map[string]EventLoop)
+	}
+	h.pausedPaths[eventName] = loop
+	h.pausedHandlers[loop.Handler] = loop
+	h.loopMu.Unlock()
+
+	h.Logger("Event loop detected:", loop.String(), "- paused handler", loop.Handler, "and", eventName)
+	if h.OnEventLoop != nil {
+		h.OnEventLoop(loop)
+	}
+	return true
+}
+
+// clearChain forgets the chain once the event was dispatched
+func (h *DevWatch) clearChain() {
+	h.loopMu.Lock()
+	h.eventChain = nil
+	h.loopMu.Unlock()
+}
+
+// loopCycle returns the steps of chain from the last run for path, the part that repeats
+func loopCycle(chain []LoopStep, path string) []LoopStep {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Path == path {
+			return slices.Clone(chain[i:])
+		}
+	}
+	return nil
+}
+
+// loopPaused reports whether a handler was paused by a feedback loop
+func (h *DevWatch) loopPaused(handler FilesEventHandlers) bool {
+	h.loopMu.Lock()
+	defer h.loopMu.Unlock()
+	_, paused := h.pausedHandlers[handlerName(handler)]
+	return paused
+}
+
+// startRun collects the writes announced by the handler run starting now
+func (h *DevWatch) startRun() time.Time {
+	h.loopMu.Lock()
+	defer h.loopMu.Unlock()
+	h.running, h.runWrites = true, nil
+	return time.Now()
+}
+
+// announceRunWrites adds paths announced through ExpectWrite to the current run
+func (h *DevWatch) announceRunWrites(paths []string) {
+	h.loopMu.Lock()
+	defer h.loopMu.Unlock()
+	if h.running {
+		h.runWrites = append(h.runWrites, paths...)
+	}
+}
+
+// recordRun remembers the window of a handler run for the event being dispatched
+func (h *DevWatch) recordRun(handler FilesEventHandlers, filePath string, start time.Time) {
+	h.loopMu.Lock()
+	defer h.loopMu.Unlock()
+	name := handlerName(handler)
+	chain := append(slices.Clip(h.eventChain), LoopStep{Path: filePath, Handler: name})
+	run := handlerRun{start: start, end: time.Now(), handler: name, trigger: filePath, writes: h.runWrites, chain: chain}
+	h.running, h.runWrites = false, nil
+	h.handlerRuns = append(h.handlerRuns, run)
+	if len(h.handlerRuns) > maxHandlerRuns {
+		h.handlerRuns = slices.Delete(h.handlerRuns, 0, len(h.handlerRuns)-maxHandlerRuns)
+	}
+}
+
+// Loops returns the feedback loops whose handlers and paths are paused.
+func (h *DevWatch) Loops() []EventLoop {
+	h.loopMu.Lock()
+	defer h.loopMu.Unlock()
+	var loops []EventLoop
+	for _, loop := range h.pausedPaths {
+		loops = append(loops, loop)
+	}
+	slices.SortFunc(loops, func(a, b EventLoop) int { return strings.Compare(a.Path, b.Path) })
+	return loops
+}
+
+// ResumeLoops resumes the handlers and paths paused by feedback loops,
+// eg: once the handler output was added to UnobservedFiles.
+func (h *DevWatch) ResumeLoops() {
+	h.loopMu.Lock()
+	defer h.loopMu.Unlock()
+	h.pausedPaths, h.pausedHandlers = nil, nil
+	h.handlerRuns, h.eventChain = nil, nil
+}
@@ -0,0 +1,192 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// OutputHandler writes output on every run, misconfigured when another
+// handler watching output writes back one of its inputs
+type OutputHandler struct {
+	RecordingHandler
+	output string
+}
+
+func (h *OutputHandler) NewFileEvent(fileName, extension, filePath, event string) error {
+	h.RecordingHandler.NewFileEvent(fileName, extension, filePath, event)
+	return os.WriteFile(h.output, []byte(time.Now().String()), 0644)
+}
+
+// SlowHandler calls whileRunning in the middle of each run, eg: the user
+// saving files during a slow build, and writes output when set
+type SlowHandler struct {
+	RecordingHandler
+	output       string
+	whileRunning func(fileName string)
+}
+
+func (h *SlowHandler) NewFileEventResult(fileName, extension, filePath, event string) (FileEventResult, error) {
+	h.RecordingHandler.NewFileEvent(fileName, extension, filePath, event)
+	time.Sleep(10 * time.Millisecond)
+	h.whileRunning(fileName)
+	time.Sleep(10 * time.Millisecond)
+	if h.output == "" {
+		return FileEventResult{}, nil
+	}
+	if err := os.WriteFile(h.output, []byte("built from "+fileName), 0644); err != nil {
+		return FileEventResult{}, err
+	}
+	return FileEventResult{Outputs: []string{h.output}}, nil
+}
+
+func dispatchPath(t *testing.T, w *DevWatch, path string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.dispatchFileEvent(filepath.Base(path), path, OpWrite, false, info, time.Now())
+}
+
+func TestDispatchFileEvent_BreaksFeedbackLoop(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"a.txt": "a", "b.md": "b", "c.md": "c"})
+	a, b, c := filepath.Join(root, "a.txt"), filepath.Join(root, "b.md"), filepath.Join(root, "c.md")
+
+	// the generator of b.md from the .txt files and the one of a.txt from the .md files feed each other
+	generator := &OutputHandler{RecordingHandler: RecordingHandler{Name_: "generator", Extensions: []string{".txt"}}, output: b}
+	docs := &OutputHandler{RecordingHandler: RecordingHandler{Name_: "docs", Extensions: []string{".md"}}, output: a}
+	var reported []EventLoop
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{generator, docs},
+		LoopLimit:          4,
+		OnEventLoop:        func(loop EventLoop) { reported = append(reported, loop) },
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	// the user edits a.txt, then every event is written by the previous run
+	time.Sleep(10 * time.Millisecond)
+	writeModule(t, root, map[string]string{"a.txt": "user edit"})
+	for i := range 8 {
+		time.Sleep(30 * time.Millisecond) // events reach dispatch after the debounce window
+		dispatchPath(t, w, []string{a, b}[i%2])
+	}
+
+	// each handler writes the same output on every run: once seen twice it
+	// extends the chain, which reaches LoopLimit at the 7th event
+	if got, want := len(generator.Files())+len(docs.Files()), 6; got != want {
+		t.Errorf("handlers ran %d times; want the loop broken after %d runs", got, want)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("reported %v; want one loop", reported)
+	}
+	loop := reported[0]
+	if loop.Handler != "docs" || loop.Path != a {
+		t.Errorf("loop = %+v; want docs and a.txt paused", loop)
+	}
+	if len(loop.Cycle) != 2 || loop.Cycle[0].Path != a || loop.Cycle[1].Path != b {
+		t.Errorf("cycle = %+v; want a.txt -> b.md", loop.Cycle)
+	}
+	if s := loop.String(); !strings.Contains(s, "a.txt -[generator]-> ") || !strings.HasSuffix(s, "a.txt") {
+		t.Errorf("String() = %q", s)
+	}
+	if got := w.Loops(); len(got) != 1 || got[0].Path != a {
+		t.Errorf("Loops() = %v; want the paused loop", got)
+	}
+
+	// the paused handler gets no other file either
+	runs := len(docs.Files())
+	time.Sleep(10 * time.Millisecond)
+	writeModule(t, root, map[string]string{"c.md": "user edit"})
+	dispatchPath(t, w, c)
+	if got := docs.Files(); len(got) != runs {
+		t.Errorf("paused handler received %v", got[runs:])
+	}
+
+	w.ResumeLoops()
+	dispatchPath(t, w, c)
+	if got := docs.Files(); len(got) != runs+1 || got[runs] != "c.md:write" {
+		t.Errorf("handler received %v; want c.md after ResumeLoops", got)
+	}
+	if len(w.Loops()) != 0 {
+		t.Error("Loops() should be empty after ResumeLoops")
+	}
+}
+
+func TestDispatchFileEvent_UserEditsAreNotLoops(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"a.txt": "a"})
+	a := filepath.Join(root, "a.txt")
+	handler := &RecordingHandler{Name_: "txt", Extensions: []string{".txt"}}
+	var reported []EventLoop
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{handler},
+		LoopLimit:          2,
+		OnEventLoop:        func(loop EventLoop) { reported = append(reported, loop) },
+		Logger:             func(message ...any) {},
+	})
+	defer w.stopReload()
+
+	for i := range 5 {
+		time.Sleep(10 * time.Millisecond)
+		writeModule(t, root, map[string]string{"a.txt": strings.Repeat("x", i+1)})
+		dispatchPath(t, w, a)
+	}
+
+	if len(reported) != 0 || len(handler.Files()) != 5 {
+		t.Errorf("reported %v, handler ran %d times; user edits never form a loop", reported, len(handler.Files()))
+	}
+}
+
+func TestDispatchFileEvent_UserSavesDuringSlowRuns(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string            // announced by the handler
+		saves  map[string]string // file saved by the user while the handler runs for a file
+	}{
+		{"the file being built", "", map[string]string{"main.txt": "main.txt"}},
+		{"another file, outputs announced", "main.wasm", map[string]string{"main.txt": "other.txt", "other.txt": "main.txt"}},
+		{"alternating files, outputs not announced", "", map[string]string{"main.txt": "other.txt", "other.txt": "main.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeModule(t, root, map[string]string{"main.txt": "a", "other.txt": "b"})
+
+			saves := 0
+			wasm := &SlowHandler{RecordingHandler: RecordingHandler{Name_: "wasm", Extensions: []string{".txt"}}}
+			if tt.output != "" {
+				wasm.output = filepath.Join(root, tt.output)
+			}
+			wasm.whileRunning = func(fileName string) {
+				saves++ // autosave with new content every time
+				writeModule(t, root, map[string]string{tt.saves[fileName]: strings.Repeat("x", saves)})
+			}
+			var reported []EventLoop
+			w := New(&WatchConfig{
+				AppRootDir:         root,
+				FilesEventHandlers: []FilesEventHandlers{wasm},
+				OnEventLoop:        func(loop EventLoop) { reported = append(reported, loop) },
+				Logger:             func(message ...any) { t.Log(message...) },
+			})
+			defer w.stopReload()
+
+			next := "main.txt"
+			for range 12 {
+				time.Sleep(30 * time.Millisecond) // events reach dispatch after the debounce window
+				dispatchPath(t, w, filepath.Join(root, next))
+				next = tt.saves[next]
+			}
+
+			if len(reported) != 0 || len(wasm.Files()) != 12 {
+				t.Errorf("reported %v, handler ran %d times; user saves during runs are not loops", reported, len(wasm.Files()))
+			}
+		})
+	}
+}
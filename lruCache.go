@@ -0,0 +1,115 @@
+package devwatch
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache is a size and age bounded map, safe for concurrent use.
+// The least recently used entry is evicted once maxEntries is exceeded and
+// entries not touched for ttl are dropped lazily (ttl 0 disables expiry).
+type lruCache[K comparable, V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List // front = most recently used
+	items      map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	touched time.Time
+}
+
+func newLRUCache[K comparable, V any](maxEntries int, ttl time.Duration) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		items:      make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value for key and marks it as recently used
+func (c *lruCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := el.Value.(*lruEntry[K, V])
+	now := time.Now()
+	if c.expired(entry, now) {
+		c.remove(el)
+		return zero, false
+	}
+	entry.touched = now
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// Put stores value for key and evicts expired or least recently used entries
+func (c *lruCache[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.touched = now
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, touched: now})
+	}
+
+	for el := c.order.Back(); el != nil; el = c.order.Back() {
+		if len(c.items) <= c.maxEntries && !c.expired(el.Value.(*lruEntry[K, V]), now) {
+			break
+		}
+		c.remove(el)
+	}
+}
+
+// Delete removes key if present
+func (c *lruCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Clear removes every entry
+func (c *lruCache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.items)
+}
+
+// Len returns the number of entries, expired ones are dropped first
+func (c *lruCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for el := c.order.Back(); el != nil && c.expired(el.Value.(*lruEntry[K, V]), now); el = c.order.Back() {
+		c.remove(el)
+	}
+	return len(c.items)
+}
+
+func (c *lruCache[K, V]) expired(entry *lruEntry[K, V], now time.Time) bool {
+	return c.ttl > 0 && now.Sub(entry.touched) > c.ttl
+}
+
+func (c *lruCache[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*lruEntry[K, V]).key)
+}
@@ -0,0 +1,110 @@
+package devwatch
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache[string, int](2, 0)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a") // "b" is now the least recently used
+	c.Put("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d; want 2", c.Len())
+	}
+}
+
+func TestLRUCache_TTL(t *testing.T) {
+	c := newLRUCache[string, int](10, 20*time.Millisecond)
+	c.Put("a", 1)
+	time.Sleep(40 * time.Millisecond)
+
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d; want expired entries dropped", c.Len())
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be expired")
+	}
+}
+
+func TestLRUCache_Delete(t *testing.T) {
+	c := newLRUCache[string, int](10, 0)
+	c.Put("a", 1)
+	c.Delete("a")
+	c.Delete("missing")
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be deleted")
+	}
+}
+
+func TestWatchEvents_StateBoundedAndClearedOnRemove(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var calls int32
+	w := New(&WatchConfig{
+		AppRootDir:          tempDir,
+		FilesEventHandlers:  []FilesEventHandlers{&SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}},
+		MaxTrackedFiles:     3,
+		SkipUnchangedWrites: true,
+		Logger:              func(message ...any) { t.Log(message...) },
+		ExitChan:            make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		file := tempDir + "/" + name + ".css"
+		if err := os.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		watcher.Events <- fsnotify.Event{Name: file, Op: fsnotify.Create}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if got := w.Stats(); got.TrackedFiles != 3 || got.DispatchedFiles != 3 {
+		t.Errorf("Stats() = %+v; want 3 tracked and dispatched files (bounded by MaxTrackedFiles)", got)
+	}
+
+	removed := tempDir + "/e.css"
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Events <- fsnotify.Event{Name: removed, Op: fsnotify.Remove}
+	time.Sleep(100 * time.Millisecond)
+
+	w.ExitChan <- true
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchEvents did not exit in time")
+	}
+
+	if got := w.Stats(); got.TrackedFiles != 2 || got.DispatchedFiles != 2 {
+		t.Errorf("Stats() = %+v; want 2 tracked and dispatched files after removing a file", got)
+	}
+	if _, ok := w.eventState.Get(removed); ok {
+		t.Error("removed file should not keep debounce state")
+	}
+}
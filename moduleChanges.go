@@ -0,0 +1,106 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/tinywasm/depfind"
+)
+
+// moduleFiles describe the module layout: a change makes the dependency graph stale
+var moduleFiles = []string{"go.mod", "go.work"}
+
+// ModuleEventHandler is optionally implemented by Go handlers (handlers supporting
+// ".go") to be told that go.mod or go.work changed, eg: a new replace directive,
+// so they can rebuild even though none of their .go files was written.
+type ModuleEventHandler interface {
+	// NewModuleEvent receives the changed file and the event: create, remove, write, rename
+	NewModuleEvent(filePath, event string) error
+}
+
+// withDeps runs fn with the dependency graph, created on first use. The graph
+// is not safe for concurrent use: the watch loop updates it while the ownership
+// queries may run on other goroutines, so every use goes through depMu.
+func (h *DevWatch) withDeps(fn func(finder *depfind.GoDepFind)) {
+	h.depMu.Lock()
+	defer h.depMu.Unlock()
+	if h.depFinder == nil {
+		h.depFinder = depfind.New(h.AppRootDir)
+	}
+	fn(h.depFinder)
+}
+
+// invalidateDeps drops the dependency graph, the cached ownership answers and the embed index
+// so the next query rebuilds them from the current module layout
+func (h *DevWatch) invalidateDeps() {
+	h.depMu.Lock()
+	h.depFinder = depfind.New(h.AppRootDir)
+	h.depMu.Unlock()
+
+	h.initFileState()
+	h.ownerCache.Clear()
+	h.resetEmbeds()
+	h.resetDirectives()
+}
+
+// isModuleFile reports whether path is a go.mod or go.work file
+func isModuleFile(path string) bool {
+	return slices.Contains(moduleFiles, filepath.Base(path))
+}
+
+// packageChanged reports whether a .go event added or removed a package:
+// the first .go file created in a directory or the last one removed from it
+func packageChanged(path, event string) bool {
+	if filepath.Ext(path) != ".go" {
+		return false
+	}
+	switch event {
+	case OpCreate:
+		return goFilesIn(filepath.Dir(path)) == 1
+	case OpRemove, OpRename:
+		return goFilesIn(filepath.Dir(path)) == 0
+	}
+	return false
+}
+
+// goFilesIn counts the non-test .go files of a directory
+func goFilesIn(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	var count int
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".go" && !isTestFile(e.Name()) {
+			count++
+		}
+	}
+	return count
+}
+
+// handleModuleChange rebuilds the dependency graph after a go.mod or go.work
+// change and sends the module event to the Go handlers that implement it.
+// It reports whether at least one handler processed the event successfully.
+func (h *DevWatch) handleModuleChange(filePath, eventType string) bool {
+	h.invalidateDeps()
+	h.Logger("Module changed, dependency graph invalidated:", filePath)
+
+	var processed bool
+	var results handlerResults
+	for _, handler := range h.FilesEventHandlers {
+		mh, ok := handler.(ModuleEventHandler)
+		if !ok || !slices.Contains(handler.SupportedExtensions(), ".go") {
+			continue
+		}
+		err := mh.NewModuleEvent(filePath, eventType)
+		if err != nil {
+			h.Logger("Module event error:", err)
+		} else {
+			processed = true
+		}
+		h.recordResult(&results, handler, filePath, reloadAfter(handler, err), err)
+	}
+	h.scheduleResults(filePath, ".go", results)
+	return processed
+}
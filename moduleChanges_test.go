@@ -0,0 +1,160 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/tinywasm/depfind"
+)
+
+// ModuleHandler is a Go handler that also receives module events
+type ModuleHandler struct {
+	SuccessHandler
+	moduleEvents []string
+}
+
+func (h *ModuleHandler) NewModuleEvent(filePath, event string) error {
+	h.moduleEvents = append(h.moduleEvents, filepath.Base(filePath)+":"+event)
+	return nil
+}
+
+func TestPackageChanged(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.go")
+	second := filepath.Join(dir, "b.go")
+	if err := os.WriteFile(first, []byte("package a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !packageChanged(first, OpCreate) {
+		t.Error("first .go file of a directory should add a package")
+	}
+	if err := os.WriteFile(second, []byte("package a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if packageChanged(second, OpCreate) {
+		t.Error("second .go file should not add a package")
+	}
+	if packageChanged(first, OpWrite) {
+		t.Error("writes never change the package layout")
+	}
+
+	os.Remove(second)
+	if packageChanged(second, OpRemove) {
+		t.Error("package still has a .go file")
+	}
+	os.Remove(first)
+	if !packageChanged(first, OpRemove) {
+		t.Error("removing the last .go file should remove the package")
+	}
+	if packageChanged(filepath.Join(dir, "style.css"), OpCreate) {
+		t.Error("non Go files never change the package layout")
+	}
+}
+
+func TestHandleFileEvent_ModuleChanged(t *testing.T) {
+	root := t.TempDir()
+	goMod := filepath.Join(root, "go.mod")
+	if err := os.WriteFile(goMod, []byte("module example\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var goCalls, cssCalls int32
+	var reloads int64
+	server := &ModuleHandler{SuccessHandler: SuccessHandler{callCount: &goCalls, SupportedExtensions_: []string{".go"}, MainInputFile: "app/server/main.go"}}
+	assets := &ModuleHandler{SuccessHandler: SuccessHandler{callCount: &cssCalls, SupportedExtensions_: []string{".css"}}}
+
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, assets},
+		BrowserReload: func() error {
+			atomic.AddInt64(&reloads, 1)
+			return nil
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+	before := depsOf(w)
+
+	if !w.handleFileEvent("go.mod", goMod, OpWrite, false) {
+		t.Error("module event handled by a Go handler should count as processed")
+	}
+
+	if depsOf(w) == before {
+		t.Error("dependency graph should be rebuilt after go.mod changed")
+	}
+	if len(server.moduleEvents) != 1 || server.moduleEvents[0] != "go.mod:write" {
+		t.Errorf("Go handler module events = %v; want [go.mod:write]", server.moduleEvents)
+	}
+	if len(assets.moduleEvents) != 0 {
+		t.Errorf("non Go handler received module events: %v", assets.moduleEvents)
+	}
+	if goCalls != 0 {
+		t.Errorf("NewFileEvent called %d times; module changes use NewModuleEvent", goCalls)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if got := atomic.LoadInt64(&reloads); got != 1 {
+		t.Errorf("browser reloaded %d times; want 1 after the Go handler rebuilt", got)
+	}
+}
+
+func TestHandleFileEvent_NewPackageInvalidatesDeps(t *testing.T) {
+	root := t.TempDir()
+	pkgFile := filepath.Join(root, "pkg", "greet", "greet.go")
+	if err := os.MkdirAll(filepath.Dir(pkgFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New(&WatchConfig{AppRootDir: root, Logger: func(message ...any) { t.Log(message...) }})
+	before := depsOf(w)
+
+	if err := os.WriteFile(pkgFile, []byte("package greet"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.handleFileEvent("greet.go", pkgFile, OpCreate, false)
+	if depsOf(w) == before {
+		t.Error("dependency graph should be rebuilt when a package is added")
+	}
+
+	// editing an existing package without touching its imports keeps the graph
+	w.handleFileEvent("greet.go", pkgFile, OpWrite, false)
+	current := depsOf(w)
+	w.handleFileEvent("greet.go", pkgFile, OpWrite, false)
+	if depsOf(w) != current {
+		t.Error("dependency graph should be kept on regular writes")
+	}
+}
+
+func TestWatchEvents_RenamedPackageInvalidatesDeps(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"pkg/greet/greet.go": "package greet"})
+	pkgFile := filepath.Join(root, "pkg", "greet", "greet.go")
+
+	w, watcher := newPauseDevWatch(t, root)
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+	before := depsOf(w)
+
+	// the last file of the package moves out of the tree: the old path is gone
+	if err := os.Rename(pkgFile, filepath.Join(t.TempDir(), "greet.go")); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Events <- fsnotify.Event{Name: pkgFile, Op: fsnotify.Rename}
+	time.Sleep(50 * time.Millisecond)
+	if depsOf(w) == before {
+		t.Error("dependency graph should be rebuilt when a package is renamed away")
+	}
+}
+
+// depsOf returns the current dependency graph of w
+func depsOf(w *DevWatch) *depfind.GoDepFind {
+	var graph *depfind.GoDepFind
+	w.withDeps(func(finder *depfind.GoDepFind) { graph = finder })
+	return graph
+}
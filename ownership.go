@@ -0,0 +1,183 @@
+package devwatch
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/tinywasm/depfind"
+)
+
+// HandlerID identifies a handler in ownership queries: its Name() when it
+// implements NamedHandler, else its main input file.
+type HandlerID string
+
+// HandlerAllGoFiles is optionally implemented by Go handlers that are not
+// tied to a main package, eg: a test runner or a linter. When AllGoFiles
+// reports true they receive every .go change without the ownership check,
+// are left out of OwnersOf and get no .go files in InitialRegistration.
+type HandlerAllGoFiles interface {
+	AllGoFiles() bool
+}
+
+// allGoFiles reports whether a handler receives every .go change
+func allGoFiles(handler FilesEventHandlers) bool {
+	a, ok := handler.(HandlerAllGoFiles)
+	return ok && a.AllGoFiles()
+}
+
+// ownership is the cached answer of the ownership queries for one file
+type ownership struct {
+	mains     []string
+	mainsErr  error
+	hasMains  bool
+	owners    []HandlerID
+	hasOwners bool
+}
+
+// AffectedMains returns the import paths of the main packages built from the
+// .go file at path (absolute or relative to AppRootDir), eg: ["example/cmd/server"].
+// Results are cached per file until its imports or the module layout change.
+func (h *DevWatch) AffectedMains(path string) ([]string, error) {
+	path = h.absPath(path)
+	h.initFileState()
+	if o, ok := h.ownerCache.Get(path); ok && o.hasMains {
+		return slices.Clone(o.mains), o.mainsErr
+	}
+
+	mains, err := h.affectedMains(path)
+	o, _ := h.ownerCache.Get(path)
+	o.mains, o.mainsErr, o.hasMains = mains, err, true
+	h.ownerCache.Put(path, o)
+	h.rememberImports(path)
+	return slices.Clone(mains), err
+}
+
+// affectedMains asks the dependency graph for the main packages that import
+// the file's package, or are that package
+func (h *DevWatch) affectedMains(path string) (mains []string, err error) {
+	h.withDeps(func(finder *depfind.GoDepFind) {
+		mains, err = h.affectedMainsIn(finder, path)
+	})
+	return mains, err
+}
+
+func (h *DevWatch) affectedMainsIn(finder *depfind.GoDepFind, path string) ([]string, error) {
+	// candidates are found by file name only: keep those that really depend
+	// on the directory of this file
+	candidates, err := finder.GoFileComesFromMain(filepath.Base(path))
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(h.AppRootDir, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	dependents, err := finder.FindReverseDeps("./...", []string{"./" + filepath.ToSlash(rel)})
+	if err != nil {
+		return nil, err
+	}
+
+	var mains []string
+	for _, main := range candidates {
+		if slices.Contains(dependents, main) {
+			mains = appendUnique(mains, main)
+		}
+	}
+	slices.Sort(mains)
+	return mains, nil
+}
+
+// OwnersOf returns the Go handlers whose main package depends on the .go file
+// at path (absolute or relative to AppRootDir), eg: ["server", "wasm"].
+// Results are cached per file until its imports or the module layout change.
+func (h *DevWatch) OwnersOf(path string) []HandlerID {
+	path = h.absPath(path)
+	h.initFileState()
+	if o, ok := h.ownerCache.Get(path); ok && o.hasOwners {
+		return slices.Clone(o.owners)
+	}
+
+	var owners []HandlerID
+	for _, handler := range h.FilesEventHandlers {
+		if !slices.Contains(handler.SupportedExtensions(), ".go") || allGoFiles(handler) || !buildMatches(handler, path) {
+			continue
+		}
+		// an empty event only queries the graph without updating it
+		var isMine bool
+		var err error
+		h.withDeps(func(finder *depfind.GoDepFind) {
+			isMine, err = finder.ThisFileIsMine(handler.MainInputFileRelativePath(), path, "")
+		})
+		if err == nil && isMine {
+			owners = append(owners, HandlerID(handlerName(handler)))
+		}
+	}
+
+	o, _ := h.ownerCache.Get(path)
+	o.owners, o.hasOwners = owners, true
+	h.ownerCache.Put(path, o)
+	h.rememberImports(path)
+	return slices.Clone(owners)
+}
+
+// absPath resolves path against AppRootDir
+func (h *DevWatch) absPath(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(h.AppRootDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// rememberImports records the imports of a .go file so a later change can be detected
+func (h *DevWatch) rememberImports(path string) {
+	if imports, ok := parseImports(path); ok {
+		h.importCache.Put(path, imports)
+	}
+}
+
+// importsChanged reports whether a .go event may change ownership answers:
+// the file was added, removed or renamed, or its imports differ from the last
+// seen ones. Imports are known from InitialRegistration or the file creation,
+// unknown ones (beyond MaxTrackedFiles) count as changed.
+func (h *DevWatch) importsChanged(path, event string) bool {
+	if filepath.Ext(path) != ".go" || isTestFile(path) {
+		return false
+	}
+	h.initFileState()
+
+	if event != OpWrite {
+		h.importCache.Delete(path)
+		if event == OpCreate {
+			h.rememberImports(path)
+		}
+		return true
+	}
+
+	imports, ok := parseImports(path)
+	if !ok {
+		return false // being written, keep answers until it parses again
+	}
+	previous, known := h.importCache.Get(path)
+	h.importCache.Put(path, imports)
+	return !known || !slices.Equal(previous, imports)
+}
+
+// parseImports returns the sorted import paths of a .go file
+func parseImports(path string) ([]string, bool) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, false
+	}
+	imports := make([]string, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, p)
+		}
+	}
+	slices.Sort(imports)
+	return slices.Compact(imports), true
+}
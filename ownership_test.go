@@ -0,0 +1,180 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// writeModule creates the files of a Go module under root
+func writeModule(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const (
+	ownershipServerMain = "package main\n\nimport (\n\t\"example/pkg/api\"\n\t\"example/pkg/shared\"\n)\n\nfunc main() { api.Run(); shared.Use() }\n"
+	ownershipWasmMain   = "package main\n\nimport \"example/pkg/shared\"\n\nfunc main() { shared.Use() }\n"
+)
+
+func newOwnershipDevWatch(t *testing.T) (*DevWatch, string) {
+	t.Helper()
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod":               "module example\n\ngo 1.22\n",
+		"cmd/server/main.go":   ownershipServerMain,
+		"cmd/wasm/main.go":     ownershipWasmMain,
+		"pkg/api/api.go":       "package api\n\nfunc Run() {}\n",
+		"pkg/shared/shared.go": "package shared\n\nfunc Use() {}\n",
+	})
+
+	var calls int32
+	w := New(&WatchConfig{
+		AppRootDir: root,
+		FilesEventHandlers: []FilesEventHandlers{
+			&NamedSuccessHandler{Name_: "server", SuccessHandler: SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".go"}, MainInputFile: "cmd/server/main.go"}},
+			&NamedSuccessHandler{Name_: "wasm", SuccessHandler: SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".go"}, MainInputFile: "cmd/wasm/main.go"}},
+			&NamedSuccessHandler{Name_: "assets", SuccessHandler: SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}},
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+	return w, root
+}
+
+func TestAffectedMains(t *testing.T) {
+	w, root := newOwnershipDevWatch(t)
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"pkg/shared/shared.go", []string{"example/cmd/server", "example/cmd/wasm"}},
+		{"pkg/api/api.go", []string{"example/cmd/server"}},
+		{filepath.Join(root, "cmd/wasm/main.go"), []string{"example/cmd/wasm"}},
+	}
+	for _, tt := range tests {
+		got, err := w.AffectedMains(tt.path)
+		if err != nil {
+			t.Fatalf("AffectedMains(%q): %v", tt.path, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("AffectedMains(%q) = %v; want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOwnersOf_CachedUntilImportsChange(t *testing.T) {
+	w, root := newOwnershipDevWatch(t)
+	apiFile := filepath.Join(root, "pkg/api/api.go")
+	wasmMain := filepath.Join(root, "cmd/wasm/main.go")
+
+	if got := w.OwnersOf("pkg/shared/shared.go"); !slices.Equal(got, []HandlerID{"server", "wasm"}) {
+		t.Errorf("OwnersOf(shared) = %v; want [server wasm]", got)
+	}
+	if got := w.OwnersOf(apiFile); !slices.Equal(got, []HandlerID{"server"}) {
+		t.Fatalf("OwnersOf(api) = %v; want [server]", got)
+	}
+
+	// the wasm main starts importing api: answers stay cached until the event
+	wasmWithAPI := "package main\n\nimport (\n\t\"example/pkg/api\"\n\t\"example/pkg/shared\"\n)\n\nfunc main() { api.Run(); shared.Use() }\n"
+	if err := os.WriteFile(wasmMain, []byte(wasmWithAPI), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.OwnersOf(apiFile); !slices.Equal(got, []HandlerID{"server"}) {
+		t.Errorf("OwnersOf(api) = %v; want the cached [server]", got)
+	}
+
+	w.handleFileEvent("main.go", wasmMain, OpWrite, false)
+	w.stopReload()
+
+	if got := w.OwnersOf(apiFile); !slices.Equal(got, []HandlerID{"server", "wasm"}) {
+		t.Errorf("OwnersOf(api) = %v; want [server wasm] after the import change", got)
+	}
+
+	// a write keeping the imports keeps the answers
+	if w.importsChanged(wasmMain, OpWrite) {
+		t.Error("same imports should not invalidate ownership")
+	}
+	if w.ownerCache.Len() == 0 {
+		t.Error("ownership cache should be kept")
+	}
+}
+
+func TestOwnership_ConcurrentWithEvents(t *testing.T) {
+	w, root := newOwnershipDevWatch(t)
+	defer w.stopReload()
+	wasmMain := filepath.Join(root, "cmd/wasm/main.go")
+	wasmWithAPI := "package main\n\nimport (\n\t\"example/pkg/api\"\n\t\"example/pkg/shared\"\n)\n\nfunc main() { api.Run(); shared.Use() }\n"
+
+	// queries from another goroutine while the watch loop updates the graph,
+	// the import changes invalidating the cached answers each time
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for range 20 {
+			w.OwnersOf("pkg/api/api.go")
+			if _, err := w.AffectedMains("pkg/shared/shared.go"); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := range 10 {
+		content := ownershipWasmMain
+		if i%2 == 0 {
+			content = wasmWithAPI
+		}
+		if err := os.WriteFile(wasmMain, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w.handleFileEvent("main.go", wasmMain, OpWrite, false)
+	}
+	<-done
+}
+
+func TestImportsChanged_SeededByInitialRegistration(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod":         "module example\n\ngo 1.22\n",
+		"pkg/api/api.go": "package api\n\nimport \"strings\"\n\nvar Up = strings.ToUpper\n",
+	})
+	apiFile := filepath.Join(root, "pkg/api/api.go")
+
+	// no Go handler: only an import change rebuilds the graph
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{&RecordingHandler{Name_: "assets", Extensions: []string{".css"}}},
+		Logger:             func(message ...any) {},
+	})
+	defer w.stopReload()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	w.watcher = watcher
+	w.InitialRegistration()
+	before := depsOf(w)
+
+	writeModule(t, root, map[string]string{"pkg/api/api.go": "package api\n\nimport \"strings\"\n\nvar Up = strings.ToLower\n"})
+	w.handleFileEvent("api.go", apiFile, OpWrite, false)
+	if depsOf(w) != before {
+		t.Error("the first save keeping the imports should keep the graph")
+	}
+
+	writeModule(t, root, map[string]string{"pkg/api/api.go": "package api\n\nimport \"bytes\"\n\nvar Up = bytes.ToLower\n"})
+	w.handleFileEvent("api.go", apiFile, OpWrite, false)
+	if depsOf(w) == before {
+		t.Error("an import change should rebuild the graph")
+	}
+}
@@ -0,0 +1,159 @@
+package devwatch
+
+import (
+	"os"
+	"slices"
+	"time"
+)
+
+// resumeSettle is how long events are still held after the last Resume, so
+// the OS events of the final writes of a bulk operation join the batch
+const resumeSettle = 100 * time.Millisecond
+
+// heldEvent is a path changed while dispatch was paused
+type heldEvent struct {
+	fileName string
+	existed  bool // the path existed before its first held event
+}
+
+// deferredBuild is the last event of a Go build handler while held events are flushed
+type deferredBuild struct {
+	handler                              FilesEventHandlers
+	fileName, extension, filePath, event string
+}
+
+// Pause holds file events until the matching Resume, eg: around a "git pull"
+// or a migration script. Calls nest: dispatch resumes after as many Resume calls.
+// Directory events are still processed so new folders are watched.
+func (h *DevWatch) Pause() {
+	h.pauseMu.Lock()
+	defer h.pauseMu.Unlock()
+	h.pauses++
+	h.holding = true
+}
+
+// Resume ends a Pause. After the last one the held events are dispatched once
+// per path, with the final state of the file: a path written many times is one
+// write, a file created and removed while paused is dropped.
+func (h *DevWatch) Resume() {
+	h.pauseMu.Lock()
+	defer h.pauseMu.Unlock()
+	if h.pauses == 0 {
+		return
+	}
+	h.pauses--
+	if h.pauses == 0 {
+		resumed := h.resumed()
+		time.AfterFunc(resumeSettle, func() {
+			select {
+			case resumed <- struct{}{}:
+			default: // a flush is already pending
+			}
+		})
+	}
+}
+
+// Paused reports whether file events are being held.
+func (h *DevWatch) Paused() bool {
+	h.pauseMu.Lock()
+	defer h.pauseMu.Unlock()
+	return h.holding
+}
+
+// Batch pauses dispatch while fn runs, eg: a refactor writing dozens of files,
+// and returns its error. The changes are dispatched once it returns.
+func (h *DevWatch) Batch(fn func() error) error {
+	h.Pause()
+	defer h.Resume()
+	return fn()
+}
+
+// resumed signals the watch loop to flush the held events
+func (h *DevWatch) resumed() chan struct{} {
+	h.resumeOnce.Do(func() {
+		h.resumeCh = make(chan struct{}, 1)
+	})
+	return h.resumeCh
+}
+
+// hold records the event of a path while paused and reports whether it was held
+func (h *DevWatch) hold(fileName, eventName, eventType string) bool {
+	h.pauseMu.Lock()
+	defer h.pauseMu.Unlock()
+	if !h.holding {
+		return false
+	}
+	if h.held == nil {
+		h.held = make(map[string]heldEvent)
+	}
+	if _, ok := h.held[eventName]; !ok {
+		h.held[eventName] = heldEvent{fileName: fileName, existed: eventType != OpCreate}
+		h.heldOrder = append(h.heldOrder, eventName)
+	}
+	return true
+}
+
+// flushHeld dispatches the held events once per path in the order they were
+// first seen, unless dispatch was paused again meanwhile. Go build handlers
+// run once at the end, for the last path they own, against the final tree.
+func (h *DevWatch) flushHeld() {
+	h.pauseMu.Lock()
+	if h.pauses > 0 {
+		h.pauseMu.Unlock()
+		return
+	}
+	held, order := h.held, h.heldOrder
+	h.held, h.heldOrder, h.holding = nil, nil, false
+	h.pauseMu.Unlock()
+
+	h.flushing = true
+	defer h.runDeferredBuilds()
+	for _, path := range order {
+		ev := held[path]
+		info, err := os.Stat(path)
+		switch {
+		case err != nil && !ev.existed:
+			continue // created and removed while paused
+		case err != nil:
+			h.dispatchFileEvent(ev.fileName, path, OpRemove, true, nil, time.Now())
+		case info.IsDir():
+			continue
+		case ev.existed:
+			h.dispatchFileEvent(ev.fileName, path, OpWrite, false, info, time.Now())
+		default:
+			h.dispatchFileEvent(ev.fileName, path, OpCreate, false, info, time.Now())
+		}
+	}
+}
+
+// deferBuild keeps a build event of a Go handler (a .go or embedded file) during
+// a flush instead of running it, replacing its previous one. It reports whether
+// the run was deferred.
+func (h *DevWatch) deferBuild(handler FilesEventHandlers, fileName, extension, filePath, event string) bool {
+	if !h.flushing || !slices.Contains(handler.SupportedExtensions(), ".go") || allGoFiles(handler) {
+		return false
+	}
+	if extension != ".go" && handlesExtension(handler, extension) {
+		return false // eg: the .js files of a wasm handler are not builds
+	}
+	build := deferredBuild{handler: handler, fileName: fileName, extension: extension, filePath: filePath, event: event}
+	name := handlerName(handler)
+	if i := slices.IndexFunc(h.flushBuilds, func(b deferredBuild) bool { return handlerName(b.handler) == name }); i >= 0 {
+		h.flushBuilds[i] = build
+		return true
+	}
+	h.flushBuilds = append(h.flushBuilds, build)
+	return true
+}
+
+// runDeferredBuilds ends a flush: each Go build handler runs once
+func (h *DevWatch) runDeferredBuilds() {
+	builds := h.flushBuilds
+	h.flushing, h.flushBuilds = false, nil
+	for _, b := range builds {
+		var results handlerResults
+		reload, err := h.runHandler(b.handler, b.fileName, b.extension, b.filePath, b.event)
+		h.recordResult(&results, b.handler, b.filePath, reload, err)
+		h.scheduleResults(b.filePath, b.extension, results)
+	}
+}
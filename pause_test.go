@@ -0,0 +1,215 @@
+package devwatch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// runWatchLoop starts watchEvents and returns a func stopping it
+func runWatchLoop(t *testing.T, w *DevWatch) func() {
+	t.Helper()
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+	return func() {
+		w.ExitChan <- true
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("watchEvents did not exit in time")
+		}
+	}
+}
+
+func newPauseDevWatch(t *testing.T, root string, handlers ...FilesEventHandlers) (*DevWatch, *fsnotify.Watcher) {
+	t.Helper()
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: handlers,
+		Logger:             func(message ...any) { t.Log(message...) },
+		ExitChan:           make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+	return w, watcher
+}
+
+func TestPauseResume_CoalescesEvents(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"b.txt":              "b",
+		"c.txt":              "c",
+		"go.mod":             "module example\n\ngo 1.22\n",
+		"cmd/server/main.go": "package main\n\nimport \"example/pkg/api\"\n\nfunc main() { api.Run() }\n",
+		"pkg/api/api.go":     "package api\n\nfunc Run() {}\n",
+	})
+	a, b, c, tmp := filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt"), filepath.Join(root, "c.txt"), filepath.Join(root, "tmp.txt")
+
+	handler := &RecordingHandler{Name_: "txt", Extensions: []string{".txt"}}
+	server := &RecordingHandler{Name_: "server", MainInput: "cmd/server/main.go", Extensions: []string{".go"}}
+	w, watcher := newPauseDevWatch(t, root, handler, server)
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+
+	w.Pause()
+	if !w.Paused() {
+		t.Fatal("Paused() = false after Pause")
+	}
+
+	writeModule(t, root, map[string]string{"a.txt": "new", "tmp.txt": "scratch"})
+	watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Create}
+	watcher.Events <- fsnotify.Event{Name: tmp, Op: fsnotify.Create}
+	for _, content := range []string{"b1", "b2", "b3"} {
+		writeModule(t, root, map[string]string{"b.txt": content})
+		watcher.Events <- fsnotify.Event{Name: b, Op: fsnotify.Write}
+	}
+	// a pull touching several files of the server
+	for i, name := range []string{"cmd/server/main.go", "pkg/api/api.go", "pkg/api/api.go", "pkg/api/extra.go"} {
+		content := fmt.Sprintf("package api\n\nfunc Run%d() {}\n", i)
+		if name == "cmd/server/main.go" {
+			content = "package main\n\nimport \"example/pkg/api\"\n\nfunc main() { api.Run1() }\n"
+		}
+		writeModule(t, root, map[string]string{name: content})
+		watcher.Events <- fsnotify.Event{Name: filepath.Join(root, name), Op: fsnotify.Write}
+	}
+	writeModule(t, root, map[string]string{"a.txt": "new edited"})
+	watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Write}
+	if err := os.Remove(tmp); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Events <- fsnotify.Event{Name: tmp, Op: fsnotify.Remove}
+	if err := os.Remove(c); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Events <- fsnotify.Event{Name: c, Op: fsnotify.Remove}
+
+	time.Sleep(150 * time.Millisecond)
+	if got := append(handler.Files(), server.Files()...); len(got) != 0 {
+		t.Fatalf("handlers received %v while paused", got)
+	}
+
+	w.Resume()
+	time.Sleep(resumeSettle + 150*time.Millisecond)
+
+	want := []string{"a.txt:create", "b.txt:write", "c.txt:remove"}
+	if got := handler.Files(); !slices.Equal(got, want) {
+		t.Errorf("handler received %v; want %v", got, want)
+	}
+	// one build for the whole batch, with the last path the server owns
+	if got := server.Files(); !slices.Equal(got, []string{"extra.go:write"}) {
+		t.Errorf("server received %v; want a single build for extra.go", got)
+	}
+	if w.Paused() {
+		t.Error("Paused() = true after the flush")
+	}
+}
+
+func TestPauseResume_Nested(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"a.txt": "a"})
+	a := filepath.Join(root, "a.txt")
+
+	handler := &RecordingHandler{Name_: "txt", Extensions: []string{".txt"}}
+	w, watcher := newPauseDevWatch(t, root, handler)
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+
+	w.Pause()
+	w.Pause()
+	watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Write}
+
+	w.Resume()
+	time.Sleep(resumeSettle + 100*time.Millisecond)
+	if got := handler.Files(); len(got) != 0 {
+		t.Fatalf("handler received %v before the last Resume", got)
+	}
+
+	w.Resume()
+	w.Resume() // extra calls are ignored
+	time.Sleep(resumeSettle + 100*time.Millisecond)
+	if got := handler.Files(); !slices.Equal(got, []string{"a.txt:write"}) {
+		t.Errorf("handler received %v; want one write after the last Resume", got)
+	}
+}
+
+func TestPauseResume_PendingTrailingEvent(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{"a.txt": "a"})
+	a := filepath.Join(root, "a.txt")
+
+	handler := &RecordingHandler{Name_: "txt", Extensions: []string{".txt"}}
+	w, watcher := newPauseDevWatch(t, root, handler)
+	w.ExtensionTiming = map[string]Timing{".txt": {Debounce: 80 * time.Millisecond, Mode: DebounceTrailing}}
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+
+	// the window of the write is still open when the batch starts
+	writeModule(t, root, map[string]string{"a.txt": "edit"})
+	watcher.Events <- fsnotify.Event{Name: a, Op: fsnotify.Write}
+	time.Sleep(20 * time.Millisecond)
+	w.Pause()
+
+	time.Sleep(150 * time.Millisecond)
+	if got := handler.Files(); len(got) != 0 {
+		t.Fatalf("handler received %v while paused", got)
+	}
+
+	w.Resume()
+	time.Sleep(resumeSettle + 100*time.Millisecond)
+	if got := handler.Files(); !slices.Equal(got, []string{"a.txt:write"}) {
+		t.Errorf("handler received %v; want the pending write with the flush", got)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	root := t.TempDir()
+	handler := &RecordingHandler{Name_: "txt", Extensions: []string{".txt"}}
+	w, watcher := newPauseDevWatch(t, root, handler)
+	defer watcher.Close()
+	defer w.stopReload()
+	stop := runWatchLoop(t, w)
+	defer stop()
+
+	errMigration := errors.New("migration failed")
+	err := w.Batch(func() error {
+		for i, name := range []string{"one.txt", "two.txt", "one.txt"} {
+			path := filepath.Join(root, name)
+			if err := os.WriteFile(path, []byte{byte('a' + i)}, 0644); err != nil {
+				return err
+			}
+			watcher.Events <- fsnotify.Event{Name: path, Op: fsnotify.Write}
+		}
+		time.Sleep(100 * time.Millisecond)
+		if got := handler.Files(); len(got) != 0 {
+			t.Errorf("handler received %v inside Batch", got)
+		}
+		return errMigration
+	})
+	if !errors.Is(err, errMigration) {
+		t.Errorf("Batch() = %v; want the func error", err)
+	}
+
+	time.Sleep(resumeSettle + 150*time.Millisecond)
+	want := []string{"one.txt:write", "two.txt:write"}
+	if got := handler.Files(); !slices.Equal(got, want) {
+		t.Errorf("handler received %v; want %v", got, want)
+	}
+}
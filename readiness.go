@@ -0,0 +1,62 @@
+package devwatch
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultReadyTimeout = 10 * time.Second
+	readyPollInterval   = 50 * time.Millisecond
+)
+
+// waitReady polls ReadyAddr and ReadyURL until both answer or ReadyTimeout
+// expires, so the browser doesn't reload before a rebuilt server listens.
+// It reports false on timeout. Without a configured check it returns at once.
+func (h *DevWatch) waitReady() bool {
+	if h.ReadyAddr == "" && h.ReadyURL == "" {
+		return true
+	}
+
+	timeout := h.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	client := &http.Client{Timeout: readyPollInterval * 10}
+
+	for {
+		if h.readyOnce(client) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			h.Logger("Readiness check timed out after", timeout, "reloading anyway")
+			return false
+		}
+		time.Sleep(readyPollInterval)
+	}
+}
+
+// readyOnce runs every configured check once
+func (h *DevWatch) readyOnce(client *http.Client) bool {
+	if h.ReadyAddr != "" {
+		conn, err := net.DialTimeout("tcp", h.ReadyAddr, readyPollInterval*10)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+	}
+
+	if h.ReadyURL != "" {
+		resp, err := client.Get(h.ReadyURL)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package devwatch
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitReady_NoCheckConfigured(t *testing.T) {
+	w := New(&WatchConfig{Logger: func(message ...any) { t.Log(message...) }})
+
+	start := time.Now()
+	if !w.waitReady() {
+		t.Error("waitReady should succeed without a configured check")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("waitReady took %v without a configured check", elapsed)
+	}
+}
+
+func TestWaitReady_HealthURL(t *testing.T) {
+	// server answers 503 while "restarting", then 200
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	w := New(&WatchConfig{
+		ReadyURL: ts.URL + "/health",
+		Logger:   func(message ...any) { t.Log(message...) },
+	})
+
+	if !w.waitReady() {
+		t.Fatal("waitReady should succeed once the health URL answers 200")
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("health URL polled %d times; want 3", got)
+	}
+}
+
+func TestWaitReady_TCPAddr(t *testing.T) {
+	// reserve a free port, then start listening on it later
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { ln.Close() })
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	w := New(&WatchConfig{
+		ReadyAddr: addr,
+		Logger:    func(message ...any) { t.Log(message...) },
+	})
+
+	start := time.Now()
+	if !w.waitReady() {
+		t.Fatal("waitReady should succeed once the port listens")
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("waitReady returned after %v, before the server listened", elapsed)
+	}
+}
+
+func TestWaitReady_Timeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	var logged int32
+	w := New(&WatchConfig{
+		ReadyURL:     ts.URL,
+		ReadyTimeout: 120 * time.Millisecond,
+		Logger:       func(message ...any) { atomic.AddInt32(&logged, 1) },
+	})
+
+	if w.waitReady() {
+		t.Error("waitReady should time out while the server answers 502")
+	}
+	if atomic.LoadInt32(&logged) == 0 {
+		t.Error("timeout should be logged")
+	}
+}
+
+func TestTriggerBrowserReload_WaitsForReadiness(t *testing.T) {
+	var ready atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	reloadedWhileReady := make(chan bool, 1)
+	w := New(&WatchConfig{
+		ReadyURL: ts.URL,
+		BrowserReload: func() error {
+			reloadedWhileReady <- ready.Load()
+			return nil
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ready.Store(true)
+	}()
+	w.initReloadTargets()
+	w.defaultReload.batch.add("main.go", ".go", nil)
+	w.triggerBrowserReload()
+
+	if !<-reloadedWhileReady {
+		t.Error("browser reloaded before the server was ready")
+	}
+}
@@ -0,0 +1,87 @@
+package reload
+
+import "strings"
+
+// clientScript connects to the server, preferring EventSource and falling back
+// to WebSocket. {{prefix}} is replaced with the server prefix.
+const clientScript = `(function () {
+	var prefix = "{{prefix}}";
+	function bust(url) {
+		var u = new URL(url, location.href);
+		u.searchParams.set("devwatch", Date.now());
+		return u.href;
+	}
+	function swapStylesheets() {
+		document.querySelectorAll('link[rel="stylesheet"]').forEach(function (link) {
+			var next = link.cloneNode();
+			next.href = bust(link.href);
+			next.onload = function () { link.remove(); };
+			link.after(next);
+		});
+	}
+	function refreshAssets() {
+		swapStylesheets();
+		document.querySelectorAll("img[src]").forEach(function (img) {
+			img.src = bust(img.src);
+		});
+	}
+	function clearOverlay() {
+		var overlay = document.getElementById("devwatch-overlay");
+		if (overlay) overlay.remove();
+	}
+	function showOverlay(errors) {
+		clearOverlay();
+		var overlay = document.createElement("div");
+		overlay.id = "devwatch-overlay";
+		overlay.style.cssText = "position:fixed;inset:0;z-index:2147483647;overflow:auto;" +
+			"padding:2em;background:rgba(0,0,0,.9);color:#ff6b6b;font:14px/1.5 monospace;";
+		errors.forEach(function (e) {
+			var title = document.createElement("h3");
+			title.textContent = e.handler + ": " + e.path;
+			var output = document.createElement("pre");
+			output.style.cssText = "white-space:pre-wrap;color:#eee;";
+			output.textContent = e.error;
+			overlay.appendChild(title);
+			overlay.appendChild(output);
+		});
+		document.body.appendChild(overlay);
+	}
+	var actions = {
+		reload: function (msg) {
+			if (msg.kind === "stylesheet") return swapStylesheets();
+			if (msg.kind === "asset") return refreshAssets();
+			location.reload();
+		},
+		error: function (msg) { showOverlay(msg.errors || []); },
+		clear: clearOverlay
+	};
+	function handle(data) {
+		var msg;
+		try { msg = JSON.parse(data); } catch (e) { return; }
+		var action = actions[msg.type];
+		if (action) action(msg);
+	}
+	function connectWebSocket() {
+		var proto = location.protocol === "https:" ? "wss://" : "ws://";
+		var ws = new WebSocket(proto + location.host + prefix + "/ws");
+		ws.onmessage = function (e) { handle(e.data); };
+		ws.onclose = function () { setTimeout(connectWebSocket, 1000); };
+	}
+	if (window.EventSource) {
+		var es = new EventSource(prefix + "/events");
+		es.onmessage = function (e) { handle(e.data); };
+	} else {
+		connectWebSocket();
+	}
+})();
+`
+
+// Script returns the client JavaScript that listens for reload messages.
+func (s *Server) Script() string {
+	return strings.ReplaceAll(clientScript, "{{prefix}}", s.prefix())
+}
+
+// ScriptTag returns the HTML tag that loads the client script.
+func (s *Server) ScriptTag() string {
+	return `<script src="` + s.ScriptPath() + `"></script>`
+}
@@ -0,0 +1,119 @@
+package reload
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Middleware injects the client script tag into HTML responses of next,
+// right before the closing </body> tag or at the end of the document.
+// Compressed responses and upgrade requests, eg: the WebSocket endpoint,
+// are passed through untouched.
+func (s *Server) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		iw := &injectWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(iw, r)
+		iw.finish(s.ScriptTag())
+	})
+}
+
+// injectWriter buffers HTML bodies so the script can be injected,
+// other responses are written straight through
+type injectWriter struct {
+	http.ResponseWriter
+	status      int
+	decided     bool
+	html        bool
+	wroteHeader bool
+	buf         bytes.Buffer
+}
+
+func (w *injectWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *injectWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.decide(p)
+	}
+	if w.html {
+		return w.buf.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide inspects the headers on the first write and flushes the status of non-HTML responses
+func (w *injectWriter) decide(p []byte) {
+	w.decided = true
+	header := w.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(p)
+		header.Set("Content-Type", contentType)
+	}
+	w.html = strings.HasPrefix(contentType, "text/html") && header.Get("Content-Encoding") == ""
+	if !w.html {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// Flush is forwarded for streamed non-HTML responses
+func (w *injectWriter) Flush() {
+	if w.html {
+		return
+	}
+	if !w.decided {
+		w.decide(nil)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack is forwarded so handlers can take over the connection
+func (w *injectWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.decided = true // nothing is left to write once hijacked
+	return hijacker.Hijack()
+}
+
+func (w *injectWriter) finish(tag string) {
+	if !w.decided {
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+	if !w.html {
+		return
+	}
+	body := injectScript(w.buf.Bytes(), tag)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+// injectScript places tag before the last </body>, or appends it
+func injectScript(body []byte, tag string) []byte {
+	i := bytes.LastIndex(bytes.ToLower(body), []byte("</body>"))
+	if i < 0 {
+		return append(body, tag...)
+	}
+	out := make([]byte, 0, len(body)+len(tag))
+	out = append(out, body[:i]...)
+	out = append(out, tag...)
+	return append(out, body[i:]...)
+}
@@ -0,0 +1,149 @@
+// Package reload provides a live-reload server for devwatch.
+//
+// Server exposes a Server-Sent Events endpoint, a WebSocket endpoint and the
+// client script that connects to them. Its Reload method matches
+// devwatch.WatchConfig.BrowserReload:
+//
+//	srv := reload.New()
+//	mux.Handle("/devwatch/", srv)
+//	http.ListenAndServe(":8080", srv.Middleware(mux))
+//
+//	cfg := &devwatch.WatchConfig{
+//		BrowserReloadWithPayload: srv.ReloadWithPayload,
+//		ErrorOverlay:             srv.ShowErrors,
+//	}
+package reload
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/tinywasm/devwatch"
+)
+
+// DefaultPrefix is the path under which the endpoints are served.
+const DefaultPrefix = "/devwatch"
+
+// clientBuffer is the number of messages queued per client before dropping.
+const clientBuffer = 16
+
+// Message is sent to connected browsers as JSON.
+type Message struct {
+	Type   string                    `json:"type"`             // eg: "reload", "error", "clear"
+	Kind   string                    `json:"kind,omitempty"`   // eg: "stylesheet", "asset", "full"
+	Paths  []string                  `json:"paths,omitempty"`  // changed files
+	Errors []devwatch.HandlerFailure `json:"errors,omitempty"` // build failures shown in the overlay
+}
+
+// Server broadcasts messages to browsers connected through SSE or WebSocket.
+type Server struct {
+	Prefix string // endpoints prefix. default: "/devwatch"
+
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// New returns a Server serving its endpoints under DefaultPrefix.
+func New() *Server {
+	return &Server{Prefix: DefaultPrefix}
+}
+
+func (s *Server) prefix() string {
+	if s.Prefix == "" {
+		return DefaultPrefix
+	}
+	return strings.TrimSuffix(s.Prefix, "/")
+}
+
+// EventsPath is the Server-Sent Events endpoint eg: "/devwatch/events".
+func (s *Server) EventsPath() string { return s.prefix() + "/events" }
+
+// WebSocketPath is the WebSocket endpoint eg: "/devwatch/ws".
+func (s *Server) WebSocketPath() string { return s.prefix() + "/ws" }
+
+// ScriptPath serves the client script eg: "/devwatch/client.js".
+func (s *Server) ScriptPath() string { return s.prefix() + "/client.js" }
+
+// ServeHTTP routes requests to the SSE, WebSocket and client script endpoints.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case s.EventsPath():
+		s.serveEvents(w, r)
+	case s.WebSocketPath():
+		s.serveWebSocket(w, r)
+	case s.ScriptPath():
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte(s.Script()))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Reload asks every connected browser to reload the page.
+// It can be used directly as WatchConfig.BrowserReload.
+func (s *Server) Reload() error {
+	return s.Send(Message{Type: "reload"})
+}
+
+// ReloadWithPayload asks every connected browser to apply the changes,
+// swapping stylesheets or refreshing assets in place when the kind allows it.
+// It can be used directly as WatchConfig.BrowserReloadWithPayload.
+func (s *Server) ReloadWithPayload(p devwatch.ReloadPayload) error {
+	return s.Send(Message{Type: "reload", Kind: string(p.Kind), Paths: p.Paths})
+}
+
+// ShowErrors displays the failures in an overlay on every connected browser,
+// an empty list removes the overlay.
+// It can be used directly as WatchConfig.ErrorOverlay.
+func (s *Server) ShowErrors(failures []devwatch.HandlerFailure) error {
+	if len(failures) == 0 {
+		return s.Send(Message{Type: "clear"})
+	}
+	return s.Send(Message{Type: "error", Errors: failures})
+}
+
+// Send broadcasts msg to every connected browser.
+// Clients that are too slow to keep up miss the message.
+func (s *Server) Send(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+	return nil
+}
+
+// Clients returns the number of connected browsers.
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+func (s *Server) subscribe() chan []byte {
+	ch := make(chan []byte, clientBuffer)
+	s.mu.Lock()
+	if s.clients == nil {
+		s.clients = make(map[chan []byte]struct{})
+	}
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *Server) unsubscribe(ch chan []byte) {
+	s.mu.Lock()
+	delete(s.clients, ch)
+	s.mu.Unlock()
+}
@@ -0,0 +1,305 @@
+package reload
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/devwatch"
+)
+
+// The reload hooks must accept the server without adapters
+var _ = devwatch.WatchConfig{
+	BrowserReload:            New().Reload,
+	BrowserReloadWithPayload: New().ReloadWithPayload,
+	ErrorOverlay:             New().ShowErrors,
+}
+
+// waitClients waits until n browsers are connected
+func waitClients(t *testing.T, s *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %d clients, have %d", n, s.Clients())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServer_SSE(t *testing.T) {
+	srv := New()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + srv.EventsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q; want text/event-stream", ct)
+	}
+
+	waitClients(t, srv, 1)
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			if got := strings.TrimSpace(line); got != `data: {"type":"reload"}` {
+				t.Errorf("got %q", got)
+			}
+			break
+		}
+	}
+
+	resp.Body.Close()
+	waitClients(t, srv, 0)
+}
+
+func TestServer_ReloadWithPayload(t *testing.T) {
+	srv := New()
+	ch := srv.subscribe()
+	defer srv.unsubscribe(ch)
+
+	err := srv.ReloadWithPayload(devwatch.ReloadPayload{
+		Paths:      []string{"web/style.css"},
+		Extensions: []string{".css"},
+		Kind:       devwatch.ReloadStylesheet,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"type":"reload","kind":"stylesheet","paths":["web/style.css"]}`
+	if got := string(<-ch); got != want {
+		t.Errorf("message = %s; want %s", got, want)
+	}
+}
+
+// dialWebSocket performs the handshake on path and returns the connection
+func dialWebSocket(t *testing.T, url, path string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// handshake example from RFC 6455
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	conn.Write([]byte("GET " + path + " HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		conn.Close()
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		t.Fatalf("status = %d; want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+	return conn, reader
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	srv := New()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conn, reader := dialWebSocket(t, ts.URL, srv.WebSocketPath())
+	defer conn.Close()
+
+	waitClients(t, srv, 1)
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	op, payload, err := readFrame(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op != opText || string(payload) != `{"type":"reload"}` {
+		t.Errorf("frame = %d %q; want text reload message", op, payload)
+	}
+
+	// masked close frame from the client unregisters it
+	conn.Write([]byte{0x80 | opClose, 0x80, 1, 2, 3, 4})
+	waitClients(t, srv, 0)
+}
+
+func TestServer_WebSocketRequiresUpgrade(t *testing.T) {
+	srv := New()
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, srv.WebSocketPath(), nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d; want 400", rec.Code)
+	}
+}
+
+func TestServer_Script(t *testing.T) {
+	srv := &Server{Prefix: "/live/"}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live/client.js", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `var prefix = "/live";`) {
+		t.Errorf("script does not use the server prefix:\n%s", body)
+	}
+	if strings.Contains(body, "{{prefix}}") {
+		t.Error("script placeholder was not replaced")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	srv := New()
+	tag := srv.ScriptTag()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"before closing body", "text/html; charset=utf-8", "<html><body><p>hi</p></BODY></html>",
+			"<html><body><p>hi</p>" + tag + "</BODY></html>"},
+		{"appended without body tag", "text/html", "<p>fragment</p>", "<p>fragment</p>" + tag},
+		{"sniffed html", "", "<!DOCTYPE html><html><body></body></html>",
+			"<!DOCTYPE html><html><body>" + tag + "</body></html>"},
+		{"json untouched", "application/json", `{"body":"</body>"}`, `{"body":"</body>"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Header().Set("Content-Length", "1")
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte(tt.body))
+			})
+
+			rec := httptest.NewRecorder()
+			srv.Middleware(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != http.StatusAccepted {
+				t.Errorf("status = %d; want 202", rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware_UpdatesContentLength(t *testing.T) {
+	srv := New()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<body></body>"))
+	})
+
+	ts := httptest.NewServer(srv.Middleware(next))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	want := int64(len("<body></body>") + len(srv.ScriptTag()))
+	if resp.ContentLength != want {
+		t.Errorf("ContentLength = %d; want %d", resp.ContentLength, want)
+	}
+}
+
+func TestMiddleware_WebSocket(t *testing.T) {
+	srv := New()
+	mux := http.NewServeMux()
+	mux.Handle(DefaultPrefix+"/", srv)
+	ts := httptest.NewServer(srv.Middleware(mux))
+	defer ts.Close()
+
+	conn, reader := dialWebSocket(t, ts.URL, srv.WebSocketPath())
+	defer conn.Close()
+
+	waitClients(t, srv, 1)
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, payload, err := readFrame(reader); err != nil || string(payload) != `{"type":"reload"}` {
+		t.Errorf("frame = %q, %v; want the reload message through the middleware", payload, err)
+	}
+}
+
+func TestMiddleware_Hijack(t *testing.T) {
+	srv := New()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		rw.Flush()
+	})
+	ts := httptest.NewServer(srv.Middleware(next))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "hijacked" {
+		t.Errorf("body = %q; want the hijacked response", body)
+	}
+}
+
+func TestServer_ShowErrors(t *testing.T) {
+	srv := New()
+	ch := srv.subscribe()
+	defer srv.unsubscribe(ch)
+
+	srv.ShowErrors([]devwatch.HandlerFailure{{Handler: "wasm", Path: "main.go", Error: "undefined: x"}})
+	want := `{"type":"error","errors":[{"handler":"wasm","path":"main.go","error":"undefined: x"}]}`
+	if got := string(<-ch); got != want {
+		t.Errorf("message = %s; want %s", got, want)
+	}
+
+	srv.ShowErrors(nil)
+	if got := string(<-ch); got != `{"type":"clear"}` {
+		t.Errorf("message = %s; want clear", got)
+	}
+}
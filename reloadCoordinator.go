@@ -0,0 +1,89 @@
+package devwatch
+
+import "slices"
+
+// HandlerFailure is the error a handler returned while processing a batch.
+type HandlerFailure struct {
+	Handler string `json:"handler"` // handler name, see NamedHandler
+	Path    string `json:"path"`    // file that triggered the handler
+	Error   string `json:"error"`   // eg: compiler output
+}
+
+// isBlocking reports whether a failure of handler cancels the batch reload
+func (h *DevWatch) isBlocking(handler FilesEventHandlers) bool {
+	return slices.Contains(h.BlockingHandlers, handlerName(handler))
+}
+
+// fail records the latest failure of a blocking handler in the batch
+func (b *reloadBatch) fail(f HandlerFailure) {
+	b.pass(f.Handler)
+	b.failures = append(b.failures, f)
+}
+
+// pass clears the failure of a blocking handler that succeeded later in the batch
+func (b *reloadBatch) pass(handler string) {
+	b.failures = slices.DeleteFunc(b.failures, func(f HandlerFailure) bool {
+		return f.Handler == handler
+	})
+}
+
+// coordinateReload applies a finished batch of a target: AfterBuild runs for the
+// handlers that succeeded, even when a blocking handler failed, so the server they
+// built is restarted. While a blocking handler is failing, in this batch or an
+// earlier one, the reload is skipped and the failures are sent to ErrorOverlay.
+// The changes are kept until the handler passes. Otherwise a previously shown
+// overlay is cleared and the browser reloads if any handler asked for it,
+// wrapped by the BeforeReload and AfterReload hooks.
+func (h *DevWatch) coordinateReload(t *reloadTarget, batch reloadBatch) {
+	payload := batch.payload()
+	payload.Target = t.Name
+	var afterBuildErr error
+	if h.AfterBuild != nil && len(batch.built) > 0 {
+		if afterBuildErr = h.AfterBuild(payload); afterBuildErr != nil {
+			h.Logger("Reload skipped, AfterBuild:", afterBuildErr)
+		}
+	}
+
+	overlay := h.overlay(t)
+	if len(batch.failures) > 0 {
+		t.overlayShown.Store(true)
+		h.Logger("Reload skipped, build failed:", len(batch.failures), "handler(s)")
+		if overlay != nil {
+			_ = overlay(slices.Clone(batch.failures))
+		}
+		return
+	}
+
+	if t.overlayShown.Swap(false) && overlay != nil {
+		_ = overlay(nil)
+	}
+	if afterBuildErr != nil {
+		return
+	}
+
+	if !batch.reload {
+		return
+	}
+
+	if h.BeforeReload != nil {
+		if err := h.BeforeReload(payload); err != nil {
+			h.Logger("Reload skipped, BeforeReload:", err)
+			return
+		}
+	}
+
+	// wait for a rebuilt backend to listen again
+	h.waitReady()
+
+	// Call synchronously so the caller completes the reload action before
+	// returning. This prevents background reload goroutines from racing
+	// with test teardown and shared counters.
+	var err error
+	if t.Reload != nil {
+		err = t.Reload(payload)
+	}
+
+	if h.AfterReload != nil {
+		h.AfterReload(payload, err)
+	}
+}
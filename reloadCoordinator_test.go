@@ -0,0 +1,197 @@
+package devwatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// ToggleHandler is a named .css handler whose error can change between events
+type ToggleHandler struct {
+	mu    sync.Mutex
+	Name_ string
+	err   error
+}
+
+func (h *ToggleHandler) SetErr(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err = err
+}
+
+func (h *ToggleHandler) NewFileEvent(fileName, extension, filePath, event string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+func (h *ToggleHandler) Name() string                      { return h.Name_ }
+func (h *ToggleHandler) SupportedExtensions() []string     { return []string{".css"} }
+func (h *ToggleHandler) MainInputFileRelativePath() string { return "" }
+func (h *ToggleHandler) UnobservedFiles() []string         { return nil }
+
+type overlayRecorder struct {
+	reloads  int
+	overlays [][]HandlerFailure
+}
+
+func newCoordinatorDevWatch(t *testing.T, rec *overlayRecorder, handlers ...FilesEventHandlers) (*DevWatch, string) {
+	t.Helper()
+	cssFile := t.TempDir() + "/style.css"
+	if err := os.WriteFile(cssFile, []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := New(&WatchConfig{
+		FilesEventHandlers: handlers,
+		BlockingHandlers:   []string{"build"},
+		BrowserReload: func() error {
+			rec.reloads++
+			return nil
+		},
+		ErrorOverlay: func(failures []HandlerFailure) error {
+			rec.overlays = append(rec.overlays, failures)
+			return nil
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+	return w, cssFile
+}
+
+// runBatch processes one event and fires its batch without waiting for the timer
+func runBatch(w *DevWatch, path string) {
+	w.handleFileEvent("style.css", path, OpWrite, false)
+	w.triggerBrowserReload()
+}
+
+func TestReloadCoordinator_BlockingFailureShowsOverlay(t *testing.T) {
+	rec := &overlayRecorder{}
+	build := &ToggleHandler{Name_: "build"}
+	other := &ToggleHandler{Name_: "lint"}
+	w, cssFile := newCoordinatorDevWatch(t, rec, build, other)
+
+	// build fails while another handler succeeds: no partial reload
+	build.SetErr(errors.New("style.css:1: unexpected }"))
+	runBatch(w, cssFile)
+
+	if rec.reloads != 0 {
+		t.Errorf("reloads = %d; want 0 when a blocking handler failed", rec.reloads)
+	}
+	if len(rec.overlays) != 1 || len(rec.overlays[0]) != 1 {
+		t.Fatalf("overlays = %v; want one overlay with one failure", rec.overlays)
+	}
+	want := HandlerFailure{Handler: "build", Path: cssFile, Error: "style.css:1: unexpected }"}
+	if rec.overlays[0][0] != want {
+		t.Errorf("failure = %+v; want %+v", rec.overlays[0][0], want)
+	}
+
+	// next success clears the overlay and reloads
+	build.SetErr(nil)
+	runBatch(w, cssFile)
+
+	if rec.reloads != 1 {
+		t.Errorf("reloads = %d; want 1 after the build recovered", rec.reloads)
+	}
+	if len(rec.overlays) != 2 || rec.overlays[1] != nil {
+		t.Errorf("overlays = %v; want the overlay cleared with nil", rec.overlays)
+	}
+
+	// further successes don't clear again
+	runBatch(w, cssFile)
+	if len(rec.overlays) != 2 {
+		t.Errorf("overlay cleared %d times; want once", len(rec.overlays)-1)
+	}
+}
+
+func TestReloadCoordinator_LaterSuccessInBatchWins(t *testing.T) {
+	rec := &overlayRecorder{}
+	build := &ToggleHandler{Name_: "build"}
+	w, cssFile := newCoordinatorDevWatch(t, rec, build)
+
+	// failure then fix inside one reload window
+	build.SetErr(errors.New("syntax error"))
+	w.handleFileEvent("style.css", cssFile, OpWrite, false)
+	build.SetErr(nil)
+	runBatch(w, cssFile)
+
+	if rec.reloads != 1 || len(rec.overlays) != 0 {
+		t.Errorf("reloads = %d, overlays = %v; want 1 reload and no overlay", rec.reloads, rec.overlays)
+	}
+}
+
+func TestReloadCoordinator_NonBlockingFailureReloads(t *testing.T) {
+	rec := &overlayRecorder{}
+	build := &ToggleHandler{Name_: "build"}
+	lint := &ToggleHandler{Name_: "lint"}
+	w, cssFile := newCoordinatorDevWatch(t, rec, build, lint)
+
+	lint.SetErr(errors.New("lint warning"))
+	runBatch(w, cssFile)
+
+	if rec.reloads != 1 || len(rec.overlays) != 0 {
+		t.Errorf("reloads = %d, overlays = %v; want 1 reload and no overlay", rec.reloads, rec.overlays)
+	}
+}
+
+func TestReloadCoordinator_FailureOutlivesUnrelatedBatches(t *testing.T) {
+	rec := &overlayRecorder{}
+	build := &ToggleHandler{Name_: "build"}
+	assets := &RecordingHandler{Name_: "assets", Extensions: []string{".js"}}
+	w, cssFile := newCoordinatorDevWatch(t, rec, build, assets)
+	jsFile := filepath.Join(filepath.Dir(cssFile), "app.js")
+	if err := os.WriteFile(jsFile, []byte("let a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	build.SetErr(errors.New("style.css:1: unexpected }"))
+	runBatch(w, cssFile)
+
+	// a batch where the failing handler doesn't run must not reload into the broken build
+	w.handleFileEvent("app.js", jsFile, OpWrite, false)
+	w.triggerBrowserReload()
+	if rec.reloads != 0 {
+		t.Errorf("reloads = %d; want 0 while build is still failing", rec.reloads)
+	}
+	for _, failures := range rec.overlays {
+		if failures == nil {
+			t.Fatal("overlay cleared while build is still failing")
+		}
+	}
+
+	build.SetErr(nil)
+	runBatch(w, cssFile)
+	if rec.reloads != 1 {
+		t.Errorf("reloads = %d; want 1 once build passes", rec.reloads)
+	}
+	if last := rec.overlays[len(rec.overlays)-1]; last != nil {
+		t.Errorf("last overlay = %v; want it cleared", last)
+	}
+}
+
+func TestReloadCoordinator_AfterBuildDespiteFailure(t *testing.T) {
+	rec := &overlayRecorder{}
+	build := &ToggleHandler{Name_: "build"}
+	server := &RecordingHandler{Name_: "server", Extensions: []string{".css"}}
+	w, cssFile := newCoordinatorDevWatch(t, rec, build, server)
+	var built [][]string
+	w.AfterBuild = func(payload ReloadPayload) error {
+		built = append(built, payload.Built)
+		return nil
+	}
+
+	// the server built fine in the batch where build failed: restart it anyway
+	build.SetErr(errors.New("style.css:1: unexpected }"))
+	runBatch(w, cssFile)
+	if len(built) != 1 || !slices.Equal(built[0], []string{"server"}) || rec.reloads != 0 {
+		t.Fatalf("AfterBuild got %v, reloads = %d; want [server] and no reload", built, rec.reloads)
+	}
+
+	// the kept changes don't run it again for the same build
+	build.SetErr(nil)
+	w.handleFileEvent("style.css", cssFile, OpWrite, false)
+	w.triggerBrowserReload()
+	if len(built) != 2 || !slices.Equal(built[1], []string{"build", "server"}) || rec.reloads != 1 {
+		t.Errorf("AfterBuild got %v, reloads = %d; want [build server] once and a reload", built, rec.reloads)
+	}
+}
@@ -0,0 +1,116 @@
+package devwatch
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ReloadKind suggests how the browser should apply a batch of changes.
+type ReloadKind string
+
+const (
+	ReloadStylesheet ReloadKind = "stylesheet" // only stylesheets changed: swap them in place
+	ReloadAsset      ReloadKind = "asset"      // only static assets and stylesheets changed: refresh them
+	ReloadFull       ReloadKind = "full"       // anything else: reload the page
+)
+
+// assetExtensions can be refreshed in the browser without a page reload
+var assetExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif", ".ico",
+	".woff", ".woff2", ".ttf", ".otf",
+}
+
+// ReloadPayload describes the changes batched into one debounced reload.
+type ReloadPayload struct {
+	Paths      []string   `json:"paths"`      // changed files
+	Extensions []string   `json:"extensions"` // eg: [".css"]
+	Handlers   []string   `json:"handlers"`   // handlers that processed the changes
+	Built      []string   `json:"built"`      // handlers that succeeded, whatever their reload policy
+	Kind       ReloadKind `json:"kind"`
+	Target     string     `json:"target,omitempty"` // ReloadTarget name, empty for the default reload
+}
+
+// NamedHandler is optionally implemented by a FilesEventHandlers to identify it
+// in reload payloads. Handlers without it are named after their main input file.
+type NamedHandler interface {
+	Name() string // eg: "server", "wasm"
+}
+
+// handlerName returns the name that identifies a handler
+func handlerName(handler FilesEventHandlers) string {
+	if n, ok := handler.(NamedHandler); ok {
+		return n.Name()
+	}
+	if main := handler.MainInputFileRelativePath(); main != "" {
+		return main
+	}
+	return fmt.Sprintf("%T", handler)
+}
+
+// reloadBatch accumulates the changes of one debounced reload
+type reloadBatch struct {
+	paths      []string
+	extensions []string
+	handlers   []string
+	built      []string         // handlers that succeeded, see ReloadPayload.Built
+	reload     bool             // at least one handler asked for a reload
+	failures   []HandlerFailure // latest failure of each blocking handler
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func (b *reloadBatch) add(path, extension string, handlers []FilesEventHandlers) {
+	b.reload = true
+	b.paths = appendUnique(b.paths, path)
+	b.extensions = appendUnique(b.extensions, extension)
+	for _, handler := range handlers {
+		b.handlers = appendUnique(b.handlers, handlerName(handler))
+	}
+}
+
+// clone copies the batch so the pending one can grow while it is applied
+func (b *reloadBatch) clone() reloadBatch {
+	return reloadBatch{
+		paths:      slices.Clone(b.paths),
+		extensions: slices.Clone(b.extensions),
+		handlers:   slices.Clone(b.handlers),
+		built:      slices.Clone(b.built),
+		reload:     b.reload,
+		failures:   slices.Clone(b.failures),
+	}
+}
+
+func (b *reloadBatch) payload() ReloadPayload {
+	return ReloadPayload{
+		Paths:      slices.Clone(b.paths),
+		Extensions: slices.Clone(b.extensions),
+		Handlers:   slices.Clone(b.handlers),
+		Built:      slices.Clone(b.built),
+		Kind:       reloadKind(b.extensions),
+	}
+}
+
+// reloadKind picks the lightest reload that applies every changed extension
+func reloadKind(extensions []string) ReloadKind {
+	if len(extensions) == 0 {
+		return ReloadFull
+	}
+	kind := ReloadStylesheet
+	for _, ext := range extensions {
+		switch {
+		case ext == ".css":
+		case slices.Contains(assetExtensions, ext):
+			kind = ReloadAsset
+		default:
+			return ReloadFull
+		}
+	}
+	return kind
+}
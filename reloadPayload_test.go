@@ -0,0 +1,138 @@
+package devwatch
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// NamedSuccessHandler is a SuccessHandler with a name
+type NamedSuccessHandler struct {
+	SuccessHandler
+	Name_ string
+}
+
+func (h *NamedSuccessHandler) Name() string {
+	return h.Name_
+}
+
+func TestReloadKind(t *testing.T) {
+	tests := []struct {
+		extensions []string
+		want       ReloadKind
+	}{
+		{[]string{".css"}, ReloadStylesheet},
+		{[]string{".png"}, ReloadAsset},
+		{[]string{".css", ".woff2"}, ReloadAsset},
+		{[]string{".css", ".js"}, ReloadFull},
+		{[]string{".go"}, ReloadFull},
+		{nil, ReloadFull},
+	}
+	for _, tt := range tests {
+		if got := reloadKind(tt.extensions); got != tt.want {
+			t.Errorf("reloadKind(%v) = %q; want %q", tt.extensions, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	named := &NamedSuccessHandler{Name_: "assets"}
+	withMain := &SuccessHandler{MainInputFile: "app/server/main.go"}
+	anonymous := &SuccessHandler{}
+
+	if got := handlerName(named); got != "assets" {
+		t.Errorf("handlerName(named) = %q", got)
+	}
+	if got := handlerName(withMain); got != "app/server/main.go" {
+		t.Errorf("handlerName(withMain) = %q", got)
+	}
+	if got := handlerName(anonymous); got != "*devwatch.SuccessHandler" {
+		t.Errorf("handlerName(anonymous) = %q", got)
+	}
+}
+
+func TestWatchEvents_ReloadWithPayload(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := tempDir + "/style.css"
+	pngFile := tempDir + "/logo.png"
+	for _, f := range []string{cssFile, pngFile} {
+		if err := os.WriteFile(f, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calls int32
+	payloads := make(chan ReloadPayload, 10)
+	plainReloads := make(chan struct{}, 10)
+	w := New(&WatchConfig{
+		AppRootDir: tempDir,
+		FilesEventHandlers: []FilesEventHandlers{&NamedSuccessHandler{
+			SuccessHandler: SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css", ".png"}},
+			Name_:          "assets",
+		}},
+		BrowserReload: func() error {
+			plainReloads <- struct{}{}
+			return nil
+		},
+		BrowserReloadWithPayload: func(p ReloadPayload) error {
+			payloads <- p
+			return nil
+		},
+		Logger:   func(message ...any) { t.Log(message...) },
+		ExitChan: make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+
+	// first batch: stylesheet only
+	watcher.Events <- fsnotify.Event{Name: cssFile, Op: fsnotify.Write}
+	var first ReloadPayload
+	select {
+	case first = <-payloads:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for first reload")
+	}
+	if first.Kind != ReloadStylesheet || !slices.Equal(first.Paths, []string{cssFile}) {
+		t.Errorf("first payload = %+v; want stylesheet reload of %s", first, cssFile)
+	}
+
+	// second batch: both files inside one reload window
+	watcher.Events <- fsnotify.Event{Name: pngFile, Op: fsnotify.Write}
+	os.WriteFile(cssFile, []byte("body { color: red }"), 0644)
+	watcher.Events <- fsnotify.Event{Name: cssFile, Op: fsnotify.Write}
+	var second ReloadPayload
+	select {
+	case second = <-payloads:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for second reload")
+	}
+
+	w.ExitChan <- true
+	<-done
+
+	want := ReloadPayload{
+		Paths:      []string{pngFile, cssFile},
+		Extensions: []string{".png", ".css"},
+		Handlers:   []string{"assets"},
+		Kind:       ReloadAsset,
+	}
+	if !slices.Equal(second.Paths, want.Paths) || !slices.Equal(second.Extensions, want.Extensions) ||
+		!slices.Equal(second.Handlers, want.Handlers) || second.Kind != want.Kind {
+		t.Errorf("second payload = %+v; want %+v", second, want)
+	}
+	if len(plainReloads) != 0 {
+		t.Error("BrowserReload should not be called when BrowserReloadWithPayload is set")
+	}
+}
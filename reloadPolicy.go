@@ -0,0 +1,75 @@
+package devwatch
+
+// ReloadPolicy decides whether an event processed by a handler reloads the browser.
+type ReloadPolicy int
+
+const (
+	// ReloadOnSuccess reloads when the handler succeeds (default).
+	ReloadOnSuccess ReloadPolicy = iota
+	// ReloadNever never reloads, eg: a backend handler whose server is restarted
+	// in WatchConfig.AfterBuild and serves no page.
+	ReloadNever
+	// ReloadAlways reloads even when the handler returns an error.
+	ReloadAlways
+	// ReloadPerCall lets the handler decide on each event through FileEventResult.Reload.
+	ReloadPerCall
+)
+
+// HandlerReloadPolicy is optionally implemented by a FilesEventHandlers to
+// choose its ReloadPolicy. Handlers without it use ReloadOnSuccess.
+type HandlerReloadPolicy interface {
+	ReloadPolicy() ReloadPolicy
+}
+
+// FileEventResult is returned by handlers implementing ResultFileEvent.
+type FileEventResult struct {
+	Reload  bool     // reload the browser for this event, used with ReloadPerCall
+	Outputs []string // files the handler wrote eg: ["web/public/main.wasm"], see DevWatch.ExpectWrite
+}
+
+// ResultFileEvent is optionally implemented by a FilesEventHandlers to return a
+// result with each event. When present it is called instead of NewFileEvent.
+type ResultFileEvent interface {
+	NewFileEventResult(fileName, extension, filePath, event string) (FileEventResult, error)
+}
+
+// runHandler delivers the event to the handler and reports whether it
+// requests a browser reload according to its policy. The outputs the
+// handler returns are expected writes, and the run is recorded to trace
+// feedback loops.
+func (h *DevWatch) runHandler(handler FilesEventHandlers, fileName, extension, filePath, event string) (reload bool, err error) {
+	defer h.recordRun(handler, filePath, h.startRun())
+
+	rh, ok := handler.(ResultFileEvent)
+	if !ok {
+		err = handler.NewFileEvent(fileName, extension, filePath, event)
+		return reloadAfter(handler, err), err
+	}
+
+	result, err := rh.NewFileEventResult(fileName, extension, filePath, event)
+	h.ExpectWrite(result.Outputs...)
+	if handlerPolicy(handler) == ReloadPerCall {
+		return err == nil && result.Reload, err
+	}
+	return reloadAfter(handler, err), err
+}
+
+// handlerPolicy returns the reload policy of a handler
+func handlerPolicy(handler FilesEventHandlers) ReloadPolicy {
+	if p, ok := handler.(HandlerReloadPolicy); ok {
+		return p.ReloadPolicy()
+	}
+	return ReloadOnSuccess
+}
+
+// reloadAfter reports whether a handler run that returned err asks for a
+// reload, for runs without a per-call result
+func reloadAfter(handler FilesEventHandlers, err error) bool {
+	switch handlerPolicy(handler) {
+	case ReloadNever:
+		return false
+	case ReloadAlways:
+		return true
+	}
+	return err == nil
+}
@@ -0,0 +1,113 @@
+package devwatch
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// PolicyHandler returns err and declares a reload policy
+type PolicyHandler struct {
+	Policy ReloadPolicy
+	Err    error
+}
+
+func (h *PolicyHandler) NewFileEvent(fileName, extension, filePath, event string) error {
+	return h.Err
+}
+func (h *PolicyHandler) SupportedExtensions() []string     { return []string{".css"} }
+func (h *PolicyHandler) MainInputFileRelativePath() string { return "" }
+func (h *PolicyHandler) UnobservedFiles() []string         { return nil }
+func (h *PolicyHandler) ReloadPolicy() ReloadPolicy        { return h.Policy }
+
+// DecidingHandler decides the reload per call
+type DecidingHandler struct {
+	PolicyHandler
+	Reload bool
+}
+
+func (h *DecidingHandler) NewFileEventResult(fileName, extension, filePath, event string) (FileEventResult, error) {
+	return FileEventResult{Reload: h.Reload}, h.Err
+}
+
+func TestRunHandler_ReloadPolicy(t *testing.T) {
+	failure := errors.New("build failed")
+	tests := []struct {
+		name    string
+		handler FilesEventHandlers
+		want    bool
+	}{
+		{"default reloads on success", &SuccessHandler{callCount: new(int32)}, true},
+		{"default skips on error", &ErrorHandler{callCount: new(int32)}, false},
+		{"never", &PolicyHandler{Policy: ReloadNever}, false},
+		{"always on error", &PolicyHandler{Policy: ReloadAlways, Err: failure}, true},
+		{"per call yes", &DecidingHandler{PolicyHandler: PolicyHandler{Policy: ReloadPerCall}, Reload: true}, true},
+		{"per call no", &DecidingHandler{PolicyHandler: PolicyHandler{Policy: ReloadPerCall}, Reload: false}, false},
+		{"per call error", &DecidingHandler{PolicyHandler: PolicyHandler{Policy: ReloadPerCall, Err: failure}, Reload: true}, false},
+		{"per call without result falls back to success", &PolicyHandler{Policy: ReloadPerCall}, true},
+		{"result ignored without per call policy", &DecidingHandler{Reload: false}, true},
+	}
+
+	w := New(&WatchConfig{Logger: func(message ...any) {}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := w.runHandler(tt.handler, "a.css", ".css", "/a.css", OpWrite); got != tt.want {
+				t.Errorf("reload = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchEvents_ReloadNeverHandler(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := tempDir + "/style.css"
+	if err := os.WriteFile(cssFile, []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reloads int64
+	var built atomic.Value
+	handler := &PolicyHandler{Policy: ReloadNever}
+	w := New(&WatchConfig{
+		AppRootDir:         tempDir,
+		FilesEventHandlers: []FilesEventHandlers{handler},
+		BrowserReload: func() error {
+			atomic.AddInt64(&reloads, 1)
+			return nil
+		},
+		// eg: a supervisor restarting the server the handler built
+		AfterBuild: func(payload ReloadPayload) error {
+			built.Store(payload.Built)
+			return nil
+		},
+		Logger:   func(message ...any) { t.Log(message...) },
+		ExitChan: make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+
+	watcher.Events <- fsnotify.Event{Name: cssFile, Op: fsnotify.Write}
+	time.Sleep(150 * time.Millisecond)
+	w.ExitChan <- true
+	<-done
+
+	if got := atomic.LoadInt64(&reloads); got != 0 {
+		t.Errorf("browser reloaded %d times; want 0 for a ReloadNever handler", got)
+	}
+	if got, _ := built.Load().([]string); len(got) != 1 || got[0] != handlerName(handler) {
+		t.Errorf("AfterBuild received Built = %v; want the ReloadNever handler", got)
+	}
+}
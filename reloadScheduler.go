@@ -0,0 +1,87 @@
+package devwatch
+
+import (
+	"sync"
+	"time"
+)
+
+// reloadScheduler runs fire once after the last of a series of schedule calls
+// (trailing debounce), never sooner than minInterval after the previous run.
+// Requests that arrive inside the interval are coalesced into a single run at
+// its end, and runs never overlap.
+type reloadScheduler struct {
+	fire        func()
+	minInterval time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	gen     int       // identifies the latest timer; older timers are stale
+	pending bool      // a run is scheduled
+	last    time.Time // start of the previous run
+
+	runMu sync.Mutex // serializes runs
+}
+
+func newReloadScheduler(minInterval time.Duration, fire func()) *reloadScheduler {
+	return &reloadScheduler{fire: fire, minInterval: minInterval}
+}
+
+// schedule (re)starts the countdown so fire runs wait after the latest call,
+// delayed further when the previous run was less than minInterval ago.
+func (s *reloadScheduler) schedule(wait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	at := now.Add(wait)
+	if s.minInterval > 0 && !s.last.IsZero() {
+		if earliest := s.last.Add(s.minInterval); at.Before(earliest) {
+			at = earliest
+		}
+	}
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.gen++
+	gen := s.gen
+	s.pending = true
+	s.timer = time.AfterFunc(at.Sub(now), func() { s.run(gen) })
+}
+
+// run calls fire unless the timer was replaced or stopped meanwhile
+func (s *reloadScheduler) run(gen int) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.mu.Lock()
+	if gen != s.gen || !s.pending {
+		s.mu.Unlock()
+		return
+	}
+	s.pending = false
+	s.last = time.Now()
+	s.mu.Unlock()
+
+	s.fire()
+}
+
+// stop cancels a scheduled run and waits for a running one to finish
+func (s *reloadScheduler) stop() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.pending = false
+	s.mu.Unlock()
+
+	s.runMu.Lock()
+	s.runMu.Unlock()
+}
+
+// isPending reports whether a run is scheduled
+func (s *reloadScheduler) isPending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
@@ -0,0 +1,175 @@
+package devwatch
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fireRecorder records when a scheduler ran
+type fireRecorder struct {
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (r *fireRecorder) fire() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.times = append(r.times, time.Now())
+}
+
+func (r *fireRecorder) runs() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Time(nil), r.times...)
+}
+
+func TestReloadScheduler_TrailingDebounce(t *testing.T) {
+	rec := &fireRecorder{}
+	s := newReloadScheduler(0, rec.fire)
+
+	start := time.Now()
+	for range 5 {
+		s.schedule(40 * time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !s.isPending() {
+		t.Fatal("expected a pending run")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	runs := rec.runs()
+	if len(runs) != 1 {
+		t.Fatalf("runs = %d; want 1 for the whole burst", len(runs))
+	}
+	if elapsed := runs[0].Sub(start); elapsed < 80*time.Millisecond {
+		t.Errorf("ran after %v; want the delay counted from the last call", elapsed)
+	}
+	if s.isPending() {
+		t.Error("no run should be pending after firing")
+	}
+}
+
+func TestReloadScheduler_MinIntervalCoalesces(t *testing.T) {
+	rec := &fireRecorder{}
+	s := newReloadScheduler(150*time.Millisecond, rec.fire)
+
+	s.schedule(0)
+	time.Sleep(20 * time.Millisecond)
+
+	// a storm of requests right after a reload collapses into one trailing run
+	for range 10 {
+		s.schedule(0)
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	runs := rec.runs()
+	if len(runs) != 2 {
+		t.Fatalf("runs = %d; want 2 (first request and one coalesced trailing run)", len(runs))
+	}
+	if gap := runs[1].Sub(runs[0]); gap < 150*time.Millisecond {
+		t.Errorf("runs %v apart; want at least MinReloadInterval", gap)
+	}
+}
+
+func TestReloadScheduler_StopCancelsPending(t *testing.T) {
+	rec := &fireRecorder{}
+	s := newReloadScheduler(0, rec.fire)
+
+	s.schedule(30 * time.Millisecond)
+	s.stop()
+	time.Sleep(60 * time.Millisecond)
+
+	if runs := rec.runs(); len(runs) != 0 {
+		t.Errorf("runs = %d; want 0 after stop", len(runs))
+	}
+
+	// the scheduler stays usable
+	s.schedule(0)
+	time.Sleep(30 * time.Millisecond)
+	if runs := rec.runs(); len(runs) != 1 {
+		t.Errorf("runs = %d; want 1 after scheduling again", len(runs))
+	}
+}
+
+func TestReloadScheduler_StopWaitsForRun(t *testing.T) {
+	running := make(chan struct{})
+	var finished bool
+	s := newReloadScheduler(0, func() {
+		close(running)
+		time.Sleep(50 * time.Millisecond)
+		finished = true
+	})
+
+	s.schedule(0)
+	<-running
+	s.stop()
+
+	if !finished {
+		t.Error("stop returned while a run was in progress")
+	}
+}
+
+func TestReloadHooks(t *testing.T) {
+	cssFile := t.TempDir() + "/style.css"
+	if err := os.WriteFile(cssFile, []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	var after []error
+	beforeErr := errors.New("bundle failed")
+	var failBefore bool
+	var calls int32
+
+	w := New(&WatchConfig{
+		FilesEventHandlers: []FilesEventHandlers{&SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}},
+		BeforeReload: func(p ReloadPayload) error {
+			order = append(order, "before:"+string(p.Kind))
+			if failBefore {
+				return beforeErr
+			}
+			return nil
+		},
+		BrowserReload: func() error {
+			order = append(order, "reload")
+			return errors.New("no clients")
+		},
+		AfterReload: func(p ReloadPayload, err error) {
+			order = append(order, "after")
+			after = append(after, err)
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+
+	w.handleFileEvent("style.css", cssFile, OpWrite, false)
+	w.stopReload()
+	w.triggerBrowserReload()
+
+	want := []string{"before:stylesheet", "reload", "after"}
+	if len(order) != len(want) {
+		t.Fatalf("calls = %v; want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("calls = %v; want %v", order, want)
+		}
+	}
+	if len(after) != 1 || after[0] == nil || after[0].Error() != "no clients" {
+		t.Errorf("AfterReload errors = %v; want the reload error", after)
+	}
+
+	// a failing BeforeReload skips the reload and AfterReload
+	order = nil
+	failBefore = true
+	w.handleFileEvent("style.css", cssFile, OpWrite, false)
+	w.stopReload()
+	w.triggerBrowserReload()
+
+	if len(order) != 1 || order[0] != "before:stylesheet" {
+		t.Errorf("calls = %v; want only BeforeReload", order)
+	}
+}
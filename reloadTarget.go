@@ -0,0 +1,154 @@
+package devwatch
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadTarget is a named group of browser clients reloaded on its own, eg: an
+// admin UI and a public PWA served from the same repository. A change reloads
+// every target matching its path or one of the handlers that processed it;
+// changes matching no target reload BrowserReload / BrowserReloadWithPayload.
+type ReloadTarget struct {
+	Name string // eg: "admin", sent as ReloadPayload.Target
+
+	// Paths relative to AppRootDir: a directory matches every file below it
+	// eg: "web/admin", anything else is a path.Match pattern eg: "web/*.css"
+	Paths    []string
+	Handlers []string // names of the handlers whose changes reload this target, see NamedHandler
+
+	Reload       func(ReloadPayload) error    // eg: adminServer.ReloadWithPayload
+	ErrorOverlay func([]HandlerFailure) error // default: WatchConfig.ErrorOverlay
+	ReloadDelay  time.Duration                // overrides the handler timing for this target
+}
+
+// reloadTarget holds the reload state of one target: its own batch, scheduler
+// and overlay, so targets debounce and reload independently.
+type reloadTarget struct {
+	ReloadTarget
+	mu           sync.Mutex
+	batch        reloadBatch // changes since the last reload, guarded by mu
+	overlayShown atomic.Bool // ErrorOverlay is displaying failures
+	scheduler    *reloadScheduler
+}
+
+// initReloadTargets creates the default target and the configured ones once
+func (h *DevWatch) initReloadTargets() {
+	h.reloadOnce.Do(func() {
+		h.defaultReload = &reloadTarget{ReloadTarget: ReloadTarget{Reload: h.browserReload}}
+		h.defaultReload.scheduler = newReloadScheduler(h.MinReloadInterval, func() { h.triggerReload(h.defaultReload) })
+
+		for _, cfg := range h.ReloadTargets {
+			t := &reloadTarget{ReloadTarget: cfg}
+			t.scheduler = newReloadScheduler(h.MinReloadInterval, func() { h.triggerReload(t) })
+			h.reloadTargets = append(h.reloadTargets, t)
+		}
+	})
+}
+
+// browserReload is the reload of the default target
+func (h *DevWatch) browserReload(payload ReloadPayload) error {
+	if h.BrowserReloadWithPayload != nil {
+		return h.BrowserReloadWithPayload(payload)
+	}
+	if h.BrowserReload != nil {
+		return h.BrowserReload()
+	}
+	return nil
+}
+
+// targetsFor returns the targets reloaded by a change of filePath processed by handlers
+func (h *DevWatch) targetsFor(filePath string, handlers []string) []*reloadTarget {
+	h.initReloadTargets()
+
+	rel := filePath
+	if r, err := filepath.Rel(h.AppRootDir, filePath); err == nil {
+		rel = r
+	}
+	rel = filepath.ToSlash(rel)
+
+	var targets []*reloadTarget
+	for _, t := range h.reloadTargets {
+		if t.matches(rel, handlers) {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		targets = append(targets, h.defaultReload)
+	}
+	return targets
+}
+
+// matches reports whether a change of rel processed by handlers belongs to the target
+func (t *reloadTarget) matches(rel string, handlers []string) bool {
+	if pathMatches(t.Paths, rel) {
+		return true
+	}
+	for _, name := range handlers {
+		if slices.Contains(t.Handlers, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathMatches reports whether rel, slash separated and relative to AppRootDir,
+// is one of patterns: a directory matches every file below it, anything else
+// is a path.Match pattern
+func pathMatches(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if rel == pattern || strings.HasPrefix(rel, pattern+"/") {
+			return true
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// overlay returns the target's ErrorOverlay or the global one
+func (h *DevWatch) overlay(t *reloadTarget) func([]HandlerFailure) error {
+	if t.ErrorOverlay != nil {
+		return t.ErrorOverlay
+	}
+	return h.ErrorOverlay
+}
+
+// triggerReload reloads a target with the changes batched since its
+// previous reload; called by the target's scheduler
+func (h *DevWatch) triggerReload(t *reloadTarget) {
+	t.mu.Lock()
+	batch := t.batch
+	t.batch = reloadBatch{}
+	if len(batch.failures) > 0 {
+		// blocking handlers are still failing: keep the changes until they pass.
+		// AfterBuild already ran for the handlers that succeeded.
+		t.batch = batch.clone()
+		t.batch.built = nil
+	}
+	t.mu.Unlock()
+
+	h.coordinateReload(t, batch)
+}
+
+// triggerBrowserReload reloads the default target
+func (h *DevWatch) triggerBrowserReload() {
+	h.initReloadTargets()
+	h.triggerReload(h.defaultReload)
+}
+
+// stopReload cancels pending reloads of every target; used during shutdown
+func (h *DevWatch) stopReload() {
+	h.initReloadTargets()
+	h.defaultReload.scheduler.stop()
+	for _, t := range h.reloadTargets {
+		t.scheduler.stop()
+	}
+}
@@ -0,0 +1,135 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// targetRecorder records the payloads each target reloaded with
+type targetRecorder struct {
+	mu       sync.Mutex
+	payloads map[string][]ReloadPayload
+}
+
+func (r *targetRecorder) reload(name string) func(ReloadPayload) error {
+	return func(p ReloadPayload) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.payloads[name] = append(r.payloads[name], p)
+		return nil
+	}
+}
+
+func (r *targetRecorder) get(name string) []ReloadPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.payloads[name]
+}
+
+func TestReloadTarget_Matches(t *testing.T) {
+	target := &reloadTarget{ReloadTarget: ReloadTarget{
+		Paths:    []string{"web/admin/", "web/*.css"},
+		Handlers: []string{"api"},
+	}}
+
+	tests := []struct {
+		rel      string
+		handlers []string
+		want     bool
+	}{
+		{"web/admin/index.html", nil, true},
+		{"web/admin", nil, true},
+		{"web/administrator/index.html", nil, false},
+		{"web/style.css", nil, true},
+		{"web/pwa/style.css", nil, false},
+		{"app/server/main.go", []string{"server", "api"}, true},
+		{"app/server/main.go", []string{"server"}, false},
+	}
+	for _, tt := range tests {
+		if got := target.matches(tt.rel, tt.handlers); got != tt.want {
+			t.Errorf("matches(%q, %v) = %v; want %v", tt.rel, tt.handlers, got, tt.want)
+		}
+	}
+}
+
+func TestReloadTargets_ScopedReloads(t *testing.T) {
+	root := t.TempDir()
+	adminFile := filepath.Join(root, "web", "admin", "admin.css")
+	pwaFile := filepath.Join(root, "web", "pwa", "app.css")
+	for _, file := range []string{adminFile, pwaFile} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("body {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := &targetRecorder{payloads: map[string][]ReloadPayload{}}
+	var calls int32
+	w := New(&WatchConfig{
+		AppRootDir:               root,
+		FilesEventHandlers:       []FilesEventHandlers{&SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}},
+		BrowserReloadWithPayload: rec.reload("default"),
+		ReloadTargets: []ReloadTarget{
+			{Name: "admin", Paths: []string{"web/admin"}, Reload: rec.reload("admin"), ReloadDelay: 10 * time.Millisecond},
+			{Name: "pwa", Paths: []string{"web/pwa"}, Reload: rec.reload("pwa"), ReloadDelay: 150 * time.Millisecond},
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+
+	w.handleFileEvent("app.css", pwaFile, OpWrite, false)
+	w.handleFileEvent("admin.css", adminFile, OpWrite, false)
+
+	// each target debounces on its own: admin is not held back by pwa
+	time.Sleep(60 * time.Millisecond)
+	if got := rec.get("admin"); len(got) != 1 {
+		t.Fatalf("admin reloads = %d; want 1", len(got))
+	} else if got[0].Target != "admin" || len(got[0].Paths) != 1 || got[0].Paths[0] != adminFile {
+		t.Errorf("admin payload = %+v; want only its own change", got[0])
+	}
+	if got := rec.get("pwa"); len(got) != 0 {
+		t.Errorf("pwa reloads = %d; want 0 before its delay", len(got))
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if got := rec.get("pwa"); len(got) != 1 || got[0].Paths[0] != pwaFile {
+		t.Errorf("pwa reloads = %+v; want one with its own change", got)
+	}
+	if got := rec.get("default"); len(got) != 0 {
+		t.Errorf("default reloads = %d; want 0 when targets matched", len(got))
+	}
+	w.stopReload()
+}
+
+func TestReloadTargets_UnmatchedUseDefault(t *testing.T) {
+	root := t.TempDir()
+	cssFile := filepath.Join(root, "style.css")
+	if err := os.WriteFile(cssFile, []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &targetRecorder{payloads: map[string][]ReloadPayload{}}
+	var calls int32
+	w := New(&WatchConfig{
+		AppRootDir:               root,
+		FilesEventHandlers:       []FilesEventHandlers{&SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}},
+		BrowserReloadWithPayload: rec.reload("default"),
+		ReloadTargets:            []ReloadTarget{{Name: "admin", Paths: []string{"web/admin"}, Reload: rec.reload("admin")}},
+		Logger:                   func(message ...any) { t.Log(message...) },
+	})
+
+	w.handleFileEvent("style.css", cssFile, OpWrite, false)
+	time.Sleep(150 * time.Millisecond)
+
+	if got := rec.get("default"); len(got) != 1 || got[0].Target != "" {
+		t.Errorf("default reloads = %+v; want one without a target name", got)
+	}
+	if got := rec.get("admin"); len(got) != 0 {
+		t.Errorf("admin reloads = %d; want 0", len(got))
+	}
+	w.stopReload()
+}
@@ -0,0 +1,82 @@
+package devwatch
+
+import (
+	"os"
+	"time"
+)
+
+const defaultExpectWriteWindow = 2 * time.Second
+
+// expectedWrite is the state a handler left a file in
+type expectedWrite struct {
+	stamp   fileStamp
+	hash    [32]byte
+	removed bool // the handler removed the file
+	until   time.Time
+}
+
+// ExpectWrite marks the current content of paths (absolute or relative to
+// AppRootDir) as written by a handler, so the events it causes don't retrigger
+// the handlers. Call it once the files are written: for ExpectWriteWindow,
+// events finding the file with that content (or still missing, when the
+// handler removed it) are ignored, while any other content is a genuine edit.
+// Handlers implementing ResultFileEvent can return FileEventResult.Outputs instead.
+func (h *DevWatch) ExpectWrite(paths ...string) {
+	if len(paths) == 0 {
+		return
+	}
+	window := h.ExpectWriteWindow
+	if window <= 0 {
+		window = defaultExpectWriteWindow
+	}
+	now := time.Now()
+
+	abs := make([]string, len(paths))
+	for i, path := range paths {
+		abs[i] = h.absPath(path)
+	}
+	h.announceRunWrites(abs)
+
+	h.expectMu.Lock()
+	defer h.expectMu.Unlock()
+	if h.expected == nil {
+		h.expected = make(map[string]expectedWrite)
+	}
+	for path, e := range h.expected {
+		if now.After(e.until) {
+			delete(h.expected, path)
+		}
+	}
+	for _, path := range abs {
+		e := expectedWrite{until: now.Add(window)}
+		if _, err := os.Stat(path); err != nil {
+			e.removed = true
+		} else {
+			e.stamp, e.hash = h.fileSnapshot(path)
+		}
+		h.expected[path] = e
+	}
+}
+
+// selfWrite reports whether an event only reflects a write announced through ExpectWrite
+func (h *DevWatch) selfWrite(path string) bool {
+	h.expectMu.Lock()
+	e, ok := h.expected[path]
+	if ok && time.Now().After(e.until) {
+		delete(h.expected, path)
+		ok = false
+	}
+	h.expectMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return e.removed
+	}
+	if e.removed {
+		return false // recreated since
+	}
+	stamp, hash := h.fileSnapshot(path)
+	return stamp == e.stamp || hash == e.hash
+}
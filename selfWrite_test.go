@@ -0,0 +1,107 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// BundlerHandler writes its bundle on every .ts change and returns it as an output
+type BundlerHandler struct {
+	RecordingHandler
+	output string
+}
+
+func (h *BundlerHandler) NewFileEventResult(fileName, extension, filePath, event string) (FileEventResult, error) {
+	h.RecordingHandler.NewFileEvent(fileName, extension, filePath, event)
+	if err := os.WriteFile(h.output, []byte("bundle of "+fileName), 0644); err != nil {
+		return FileEventResult{}, err
+	}
+	return FileEventResult{Outputs: []string{h.output}}, nil
+}
+
+func TestExpectWrite(t *testing.T) {
+	root := t.TempDir()
+	wasm := filepath.Join(root, "web/main.wasm")
+	writeModule(t, root, map[string]string{"web/main.wasm": "build 1"})
+	w := New(&WatchConfig{AppRootDir: root, ExpectWriteWindow: 200 * time.Millisecond, Logger: func(message ...any) {}})
+
+	if w.selfWrite(wasm) {
+		t.Fatal("write not announced should not be a self write")
+	}
+
+	w.ExpectWrite("web/main.wasm")
+	if !w.selfWrite(wasm) {
+		t.Error("announced write should be a self write")
+	}
+	if !w.selfWrite(wasm) {
+		t.Error("every event of the announced write should be ignored")
+	}
+
+	// a user edit with different content gets through
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(wasm, []byte("user edit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w.selfWrite(wasm) {
+		t.Error("different content should be a genuine edit")
+	}
+
+	// removal announced by the handler
+	if err := os.Remove(wasm); err != nil {
+		t.Fatal(err)
+	}
+	w.ExpectWrite(wasm)
+	if !w.selfWrite(wasm) {
+		t.Error("announced removal should be a self write")
+	}
+	writeModule(t, root, map[string]string{"web/main.wasm": "recreated"})
+	if w.selfWrite(wasm) {
+		t.Error("a file recreated after an announced removal is a genuine edit")
+	}
+
+	// the window expires
+	w.ExpectWrite(wasm)
+	time.Sleep(250 * time.Millisecond)
+	if w.selfWrite(wasm) {
+		t.Error("announced write should expire after ExpectWriteWindow")
+	}
+}
+
+func TestDispatchFileEvent_IgnoresHandlerOutputs(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "src/app.ts")
+	bundle := filepath.Join(root, "web/main.js")
+	writeModule(t, root, map[string]string{"src/app.ts": "let a = 1", "web/main.js": ""})
+
+	bundler := &BundlerHandler{RecordingHandler: RecordingHandler{Name_: "bundler", Extensions: []string{".ts", ".js"}}, output: bundle}
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{bundler},
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	dispatch := func(path string) {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.dispatchFileEvent(filepath.Base(path), path, OpWrite, false, info, time.Now())
+	}
+
+	dispatch(source)
+	dispatch(bundle) // the bundler's own write
+	if got := bundler.Files(); len(got) != 1 || got[0] != "app.ts:write" {
+		t.Fatalf("bundler received %v; want only the source change", got)
+	}
+
+	if err := os.WriteFile(bundle, []byte("hand edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dispatch(bundle)
+	if got := bundler.Files(); len(got) != 2 || got[1] != "main.js:write" {
+		t.Errorf("bundler received %v; want the genuine edit of the output", got)
+	}
+}
@@ -0,0 +1,163 @@
+package devwatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// sendWrites runs watchEvents, sends each write after its content is on disk and
+// waits longer than the debounce window between them.
+func sendWrites(t *testing.T, w *DevWatch, watcher *fsnotify.Watcher, file string, contents ...string) {
+	t.Helper()
+
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+
+	for _, content := range contents {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		watcher.Events <- fsnotify.Event{Name: file, Op: fsnotify.Write}
+		time.Sleep(120 * time.Millisecond) // beyond the 50ms debounce window
+	}
+
+	w.ExitChan <- true
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchEvents did not exit in time")
+	}
+}
+
+func newSkipUnchangedDevWatch(t *testing.T, tempDir string, skip bool, handler FilesEventHandlers) (*DevWatch, *fsnotify.Watcher) {
+	t.Helper()
+	w := New(&WatchConfig{
+		AppRootDir:          tempDir,
+		FilesEventHandlers:  []FilesEventHandlers{handler},
+		SkipUnchangedWrites: skip,
+		Logger:              func(message ...any) { t.Log(message...) },
+		ExitChan:            make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+	return w, watcher
+}
+
+func TestSkipUnchangedWrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		skip     bool
+		contents []string
+		want     int
+	}{
+		{"disabled dispatches identical rewrites", false, []string{"a {}", "a {}", "a {}"}, 3},
+		{"enabled drops identical rewrites", true, []string{"a {}", "a {}", "a {}"}, 1},
+		{"enabled dispatches real changes", true, []string{"a {}", "b {}", "b {}", "a {}"}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cssFile := tempDir + "/style.css"
+
+			var calls int32
+			handler := &SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}
+			w, watcher := newSkipUnchangedDevWatch(t, tempDir, tt.skip, handler)
+
+			sendWrites(t, w, watcher, cssFile, tt.contents...)
+
+			if got := atomic.LoadInt32(&calls); int(got) != tt.want {
+				t.Errorf("handler called %d times; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipUnchangedWrites_RetriesAfterFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := tempDir + "/style.css"
+
+	// A failing handler never records a successful dispatch,
+	// so saving the same content again must retry it.
+	var calls int32
+	handler := &ErrorHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}
+	w, watcher := newSkipUnchangedDevWatch(t, tempDir, true, handler)
+
+	sendWrites(t, w, watcher, cssFile, "a {}", "a {}")
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("handler called %d times; want 2", got)
+	}
+}
+
+func TestSkipUnchangedWrites_SurvivesTrackedFileTTL(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := tempDir + "/style.css"
+
+	// the debounce entry expires between the writes, the dispatched content must not
+	var calls int32
+	handler := &SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}
+	w := New(&WatchConfig{
+		AppRootDir:          tempDir,
+		FilesEventHandlers:  []FilesEventHandlers{handler},
+		SkipUnchangedWrites: true,
+		TrackedFileTTL:      60 * time.Millisecond,
+		Logger:              func(message ...any) { t.Log(message...) },
+		ExitChan:            make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+
+	sendWrites(t, w, watcher, cssFile, "a {}", "a {}", "a {}")
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("handler called %d times; want 1 after TrackedFileTTL expired", got)
+	}
+}
+
+func TestSkipUnchangedWrites_SaveDuringSlowBuild(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := filepath.Join(tempDir, "style.css")
+	if err := os.WriteFile(cssFile, []byte("a {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the user saves new content while the handler still builds the old one
+	handler := &SlowHandler{RecordingHandler: RecordingHandler{Name_: "css", Extensions: []string{".css"}}}
+	handler.whileRunning = func(fileName string) {
+		if len(handler.Files()) == 1 {
+			if err := os.WriteFile(cssFile, []byte("b { color: red }"), 0644); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	w := New(&WatchConfig{
+		AppRootDir:          tempDir,
+		FilesEventHandlers:  []FilesEventHandlers{handler},
+		SkipUnchangedWrites: true,
+		Logger:              func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	dispatchPath(t, w, cssFile)
+	time.Sleep(30 * time.Millisecond)
+	dispatchPath(t, w, cssFile) // the queued event of the save
+
+	if got := handler.Files(); len(got) != 2 {
+		t.Errorf("handler received %v; want the save during the build dispatched", got)
+	}
+}
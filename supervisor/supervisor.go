@@ -0,0 +1,315 @@
+// Package supervisor runs the backend server built by a devwatch Go handler
+// and restarts it after each successful rebuild.
+//
+// The restart happens in the AfterBuild hook, once per debounced batch, before
+// the browser reloads for the server handler and waits for the new server to
+// listen (see WatchConfig.ReadyAddr). A server handler using ReloadNever is
+// still restarted, but then the browser only reloads when another handler asks:
+//
+//	server := supervisor.New("server", "./bin/server", "-port", "8080")
+//	server.Handlers = []string{"server"} // Name() of the Go handler building ./bin/server
+//	server.Logger = logger
+//	if err := server.Start(); err != nil {
+//		return err
+//	}
+//	defer server.Stop()
+//
+//	cfg := &devwatch.WatchConfig{
+//		AfterBuild: server.AfterBuild,
+//		ReadyAddr:  "localhost:8080",
+//	}
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tinywasm/devwatch"
+	"github.com/tinywasm/devwatch/internal/linelog"
+)
+
+const (
+	DefaultStopTimeout  = 5 * time.Second        // wait after StopSignal before killing
+	DefaultRestartDelay = 500 * time.Millisecond // wait before restarting a crashed process
+	DefaultMaxCrashes   = 5                      // crashes within CrashWindow that stop the restarts
+	DefaultCrashWindow  = 30 * time.Second
+)
+
+// State is the lifecycle state of the supervised process.
+type State int
+
+const (
+	Stopped   State = iota // not started or stopped by Stop
+	Running                // started and not exited
+	Exited                 // exited on its own, waiting for a restart
+	CrashLoop              // crashed MaxCrashes times within CrashWindow, restarts only after a rebuild
+)
+
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Exited:
+		return "exited"
+	case CrashLoop:
+		return "crash loop"
+	}
+	return "stopped"
+}
+
+// Status is a snapshot of the supervised process.
+type Status struct {
+	State    State
+	PID      int       // 0 when not running
+	Started  time.Time // start of the current or last process
+	Restarts int       // restarts after rebuilds and crashes
+	LastExit error     // exit error of the previous process, nil for a clean exit
+}
+
+// Process supervises one backend binary.
+type Process struct {
+	Name         string               // log prefix eg: "server"
+	Command      []string             // binary and arguments eg: ["./bin/server", "-port", "8080"]
+	Dir          string               // working directory. default: the current one
+	Env          []string             // added to the environment eg: ["ENV=dev"]
+	Handlers     []string             // Go handlers whose successful builds restart the process eg: ["server"]
+	StopSignal   os.Signal            // default: os.Interrupt
+	StopTimeout  time.Duration        // see DefaultStopTimeout
+	RestartDelay time.Duration        // see DefaultRestartDelay
+	MaxCrashes   int                  // see DefaultMaxCrashes
+	CrashWindow  time.Duration        // see DefaultCrashWindow
+	Logger       func(message ...any) // process output, prefixed with Name, and lifecycle messages
+	OnState      func(Status)         // optional, called on every state change; must not call back into the Process
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	exited  chan struct{} // closed when cmd has exited
+	gen     int           // identifies the current process, older exits are ignored
+	status  Status
+	crashes []time.Time
+	retry   *time.Timer
+}
+
+// New returns a Process running binary with args, logged as name.
+func New(name, binary string, args ...string) *Process {
+	return &Process{Name: name, Command: append([]string{binary}, args...)}
+}
+
+// Status returns a snapshot of the process state.
+func (p *Process) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// Start starts the process unless it is already running.
+func (p *Process) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status.State == Running {
+		return nil
+	}
+	return p.startLocked()
+}
+
+// Stop sends StopSignal, kills the process after StopTimeout and waits for it to exit.
+func (p *Process) Stop() error {
+	p.mu.Lock()
+	p.gen++ // the exit is expected
+	err := p.stopLocked()
+	p.crashes = nil
+	p.setState(Stopped)
+	p.mu.Unlock()
+	return err
+}
+
+// Restart stops the process, if running, and starts it again. A restart
+// ends a crash loop: the new binary may have fixed it.
+func (p *Process) Restart() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gen++
+	if err := p.stopLocked(); err != nil {
+		p.log(p.Name, "stop:", err)
+	}
+	p.crashes = nil
+	if !p.status.Started.IsZero() {
+		p.status.Restarts++
+	}
+	return p.startLocked()
+}
+
+// AfterBuild matches devwatch.WatchConfig.AfterBuild: it restarts the
+// process when one of Handlers succeeded in the batch, whatever its reload
+// policy, so a reload of the batch reaches the new server. An error skips the reload.
+func (p *Process) AfterBuild(payload devwatch.ReloadPayload) error {
+	if !slices.ContainsFunc(payload.Built, func(h string) bool { return slices.Contains(p.Handlers, h) }) {
+		return nil
+	}
+	if err := p.Restart(); err != nil {
+		return fmt.Errorf("restart %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// startLocked starts a new process, p.mu must be held
+func (p *Process) startLocked() error {
+	if p.retry != nil {
+		p.retry.Stop()
+		p.retry = nil
+	}
+	if len(p.Command) == 0 {
+		return errors.New("supervisor: empty command")
+	}
+
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Dir = p.Dir
+	if len(p.Env) > 0 {
+		cmd.Env = append(os.Environ(), p.Env...)
+	}
+	stdout := linelog.New(p.Logger, p.Name)
+	stderr := linelog.New(p.Logger, p.Name+" (stderr)")
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Start(); err != nil {
+		p.status.LastExit = err
+		p.setState(Exited)
+		return err
+	}
+
+	p.gen++
+	gen := p.gen
+	exited := make(chan struct{})
+	p.cmd, p.exited = cmd, exited
+	p.status.PID = cmd.Process.Pid
+	p.status.Started = time.Now()
+	p.setState(Running)
+	p.log(p.Name, "started, pid", cmd.Process.Pid)
+
+	go func() {
+		err := cmd.Wait()
+		stdout.Flush()
+		stderr.Flush()
+		close(exited)
+		p.exit(gen, err)
+	}()
+	return nil
+}
+
+// stopLocked stops the current process, p.mu must be held. The lock is kept
+// while waiting so no restart can race with the stop.
+func (p *Process) stopLocked() error {
+	if p.retry != nil {
+		p.retry.Stop()
+		p.retry = nil
+	}
+	cmd, exited := p.cmd, p.exited
+	if cmd == nil {
+		return nil
+	}
+	p.cmd, p.exited = nil, nil
+	select {
+	case <-exited:
+		return nil
+	default:
+	}
+
+	sig := p.StopSignal
+	if sig == nil {
+		sig = os.Interrupt
+	}
+	timeout := p.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	if err := cmd.Process.Signal(sig); err != nil {
+		cmd.Process.Kill()
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(timeout):
+	}
+	p.log(p.Name, "didn't stop after", timeout, "killing it")
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	<-exited
+	return nil
+}
+
+// exit records the exit of the process started as gen. Unexpected exits
+// are crashes: the process is restarted after RestartDelay until MaxCrashes
+// happen within CrashWindow.
+func (p *Process) exit(gen int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if gen != p.gen {
+		return // stopped or replaced on purpose
+	}
+	p.cmd, p.exited = nil, nil
+	p.status.PID = 0
+	p.status.LastExit = err
+	p.log(p.Name, "exited:", exitReason(err))
+
+	window, maxCrashes := p.CrashWindow, p.MaxCrashes
+	if window <= 0 {
+		window = DefaultCrashWindow
+	}
+	if maxCrashes <= 0 {
+		maxCrashes = DefaultMaxCrashes
+	}
+	now := time.Now()
+	p.crashes = slices.DeleteFunc(append(p.crashes, now), func(t time.Time) bool { return now.Sub(t) > window })
+	if len(p.crashes) >= maxCrashes {
+		p.log(p.Name, "crash loop:", len(p.crashes), "exits in", window, "waiting for a rebuild")
+		p.setState(CrashLoop)
+		return
+	}
+
+	p.setState(Exited)
+	delay := p.RestartDelay
+	if delay <= 0 {
+		delay = DefaultRestartDelay
+	}
+	p.retry = time.AfterFunc(delay, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.gen != gen || p.status.State != Exited {
+			return
+		}
+		p.status.Restarts++
+		if err := p.startLocked(); err != nil {
+			p.log(p.Name, "restart:", err)
+		}
+	})
+}
+
+// setState updates the state and notifies OnState, p.mu must be held
+func (p *Process) setState(s State) {
+	if s != Running {
+		p.status.PID = 0
+	}
+	p.status.State = s
+	if p.OnState != nil {
+		p.OnState(p.status)
+	}
+}
+
+func (p *Process) log(message ...any) {
+	if p.Logger != nil {
+		p.Logger(message...)
+	}
+}
+
+// exitReason describes how a process ended
+func exitReason(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
@@ -0,0 +1,246 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/devwatch"
+)
+
+// server prints its start and a goodbye on SIGINT
+const server = `echo "up $$"; trap 'echo bye; exit 0' INT; while true; do sleep 0.05; done`
+
+// logRecorder collects the logged lines
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logRecorder) log(message ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, strings.TrimSuffix(fmt.Sprintln(message...), "\n"))
+}
+
+func (l *logRecorder) count(prefix string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var n int
+	for _, line := range l.lines {
+		if strings.HasPrefix(line, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func newProcess(t *testing.T, script string) (*Process, *logRecorder) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	logs := &logRecorder{}
+	p := New("server", "sh", "-c", script)
+	p.Logger = logs.log
+	t.Cleanup(func() { p.Stop() })
+	return p, logs
+}
+
+// eventually polls cond for up to 5s
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProcess_StartStop(t *testing.T) {
+	p, logs := newProcess(t, server)
+	var states []State
+	p.OnState = func(s Status) { states = append(states, s.State) }
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	st := p.Status()
+	if st.State != Running || st.PID == 0 {
+		t.Fatalf("status = %+v; want running with a pid", st)
+	}
+	eventually(t, "stdout", func() bool { return logs.count(fmt.Sprintf("server up %d", st.PID)) == 1 })
+
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Status(); got.State != Stopped || got.PID != 0 {
+		t.Errorf("status = %+v; want stopped", got)
+	}
+	if logs.count("server bye") != 1 {
+		t.Errorf("logs = %q; want the graceful shutdown output", logs.lines)
+	}
+	if !slices.Equal(states, []State{Running, Stopped}) {
+		t.Errorf("states = %v; want [running stopped]", states)
+	}
+}
+
+func TestProcess_StopEscalatesToKill(t *testing.T) {
+	p, logs := newProcess(t, `trap '' INT; echo ready; while true; do sleep 0.05; done`)
+	p.StopTimeout = 200 * time.Millisecond
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "start", func() bool { return logs.count("server ready") == 1 })
+
+	start := time.Now()
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("stop took %v; want a kill after StopTimeout", elapsed)
+	}
+	if logs.count("server didn't stop") != 1 {
+		t.Errorf("logs = %q; want the escalation logged", logs.lines)
+	}
+}
+
+func TestProcess_StderrPrefix(t *testing.T) {
+	p, logs := newProcess(t, `echo oops >&2; `+server)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "stderr", func() bool { return logs.count("server (stderr) oops") == 1 })
+}
+
+func TestProcess_AfterBuildRestartsOnBuild(t *testing.T) {
+	p, _ := newProcess(t, server)
+	p.Handlers = []string{"server"}
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	first := p.Status().PID
+
+	if err := p.AfterBuild(devwatch.ReloadPayload{Built: []string{"assets"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Status(); got.PID != first || got.Restarts != 0 {
+		t.Errorf("status = %+v; other handlers must not restart the process", got)
+	}
+
+	if err := p.AfterBuild(devwatch.ReloadPayload{Built: []string{"assets", "server"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := p.Status()
+	if got.State != Running || got.PID == first || got.Restarts != 1 {
+		t.Errorf("status = %+v; want a new running process", got)
+	}
+}
+
+func TestProcess_CrashLoop(t *testing.T) {
+	p, logs := newProcess(t, `echo boom; exit 1`)
+	p.Handlers = []string{"server"}
+	p.RestartDelay = 10 * time.Millisecond
+	p.MaxCrashes = 3
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "crash loop", func() bool { return p.Status().State == CrashLoop })
+
+	st := p.Status()
+	if st.Restarts != 2 || st.LastExit == nil {
+		t.Errorf("status = %+v; want 2 restarts before giving up", st)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := logs.count("server boom"); n != 3 {
+		t.Errorf("crashed %d times; want no restart once the loop is detected", n)
+	}
+
+	// a rebuild tries again
+	p.AfterBuild(devwatch.ReloadPayload{Built: []string{"server"}})
+	eventually(t, "restart after rebuild", func() bool { return logs.count("server boom") == 4 })
+}
+
+// goHandler is the Go handler building the server, with the default reload policy
+type goHandler struct{}
+
+func (goHandler) Name() string                      { return "server" }
+func (goHandler) MainInputFileRelativePath() string { return "cmd/server/main.go" }
+func (goHandler) SupportedExtensions() []string     { return []string{".go"} }
+func (goHandler) UnobservedFiles() []string         { return nil }
+func (goHandler) NewFileEvent(fileName, extension, filePath, event string) error {
+	return nil
+}
+
+func TestProcess_AfterBuildThenReload(t *testing.T) {
+	root := t.TempDir()
+	mainFile := filepath.Join(root, "cmd", "server", "main.go")
+	for path, content := range map[string]string{
+		filepath.Join(root, "go.mod"): "module example\n\ngo 1.22\n",
+		mainFile:                      "package main\n\nfunc main() {}\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the configuration of the package doc
+	p, _ := newProcess(t, server)
+	p.Handlers = []string{"server"}
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	first := p.Status().PID
+
+	var mu sync.Mutex
+	var reloaded []Status
+	w := devwatch.New(&devwatch.WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []devwatch.FilesEventHandlers{goHandler{}},
+		AfterBuild:         p.AfterBuild,
+		BrowserReload: func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			reloaded = append(reloaded, p.Status())
+			return nil
+		},
+		Logger:   func(message ...any) {},
+		ExitChan: make(chan bool, 1),
+	})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go w.FileWatcherStart(&wg)
+	defer func() {
+		w.ExitChan <- true
+		wg.Wait()
+	}()
+	time.Sleep(300 * time.Millisecond)
+
+	if err := os.WriteFile(mainFile, []byte("package main\n\nfunc main() { println() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the browser reload", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reloaded) > 0
+	})
+
+	// the browser reloads once the server was restarted
+	mu.Lock()
+	defer mu.Unlock()
+	if got := reloaded[0]; got.State != Running || got.PID == first || got.Restarts != 1 {
+		t.Errorf("status at reload = %+v; want the restarted server", got)
+	}
+}
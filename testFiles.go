@@ -0,0 +1,53 @@
+package devwatch
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// TestEventHandler is optionally implemented by handlers that run tests.
+// Changes to _test.go files can't affect a binary, so they skip the build
+// handlers and are delivered here with the package they belong to instead.
+type TestEventHandler interface {
+	// NewTestEvent receives the test file, its package as a path usable by
+	// "go test" eg: "./pkg/greet", and the event: create, remove, write, rename
+	NewTestEvent(filePath, packagePath, event string) error
+}
+
+// isTestFile reports whether path is a Go test file
+func isTestFile(path string) bool {
+	return strings.HasSuffix(path, "_test.go")
+}
+
+// packagePath returns the package directory of a file relative to AppRootDir
+// in "go test" form eg: "./pkg/greet", or "." for the root package
+func (h *DevWatch) packagePath(filePath string) string {
+	rel, err := filepath.Rel(h.AppRootDir, filepath.Dir(filePath))
+	if err != nil || rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// handleTestFile delivers a _test.go event to the test handlers. Test runs
+// never reload the browser, but a blocking test handler shows its failures
+// in the ErrorOverlay. It reports whether a handler processed it successfully.
+func (h *DevWatch) handleTestFile(eventName, eventType string) bool {
+	pkg := h.packagePath(eventName)
+
+	var processed bool
+	var results handlerResults
+	for _, handler := range h.FilesEventHandlers {
+		th, ok := handler.(TestEventHandler)
+		if !ok || !handlerAcceptsEvent(handler, eventType) {
+			continue
+		}
+		err := th.NewTestEvent(eventName, pkg, eventType)
+		if err == nil {
+			processed = true
+		}
+		h.recordResult(&results, handler, eventName, false, err)
+	}
+	h.scheduleResults(eventName, ".go", results)
+	return processed
+}
@@ -0,0 +1,180 @@
+package devwatch
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TestRunnerHandler records the test events it received
+type TestRunnerHandler struct {
+	RecordingHandler
+	tests []string
+	err   error
+}
+
+func (h *TestRunnerHandler) NewTestEvent(filePath, packagePath, event string) error {
+	h.tests = append(h.tests, filepath.Base(filePath)+"@"+packagePath+":"+event)
+	return h.err
+}
+
+func newTestFilesProject(t *testing.T) (root string, server *RecordingHandler, runner *TestRunnerHandler) {
+	t.Helper()
+	root = t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod":                    "module example\n\ngo 1.22\n",
+		"cmd/server/main.go":        "package main\n\nimport \"example/pkg/greet\"\n\nfunc main() { greet.Hi() }\n",
+		"pkg/greet/greet.go":        "package greet\n\nfunc Hi() {}\n",
+		"pkg/greet/greet_test.go":   "package greet\n\nimport \"testing\"\n\nfunc TestHi(t *testing.T) { Hi() }\n",
+		"root_test.go":              "package main\n",
+		"cmd/server/server_test.go": "package main\n",
+	})
+	server = &RecordingHandler{Name_: "server", MainInput: "cmd/server/main.go", Extensions: []string{".go"}}
+	runner = &TestRunnerHandler{RecordingHandler: RecordingHandler{Name_: "tests", Extensions: []string{".go"}}}
+	return root, server, runner
+}
+
+func TestHandleFileEvent_TestFilesGoToTestHandlers(t *testing.T) {
+	root, server, runner := newTestFilesProject(t)
+	var reloads int
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, runner},
+		BrowserReload: func() error {
+			reloads++
+			return nil
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+
+	testFile := filepath.Join(root, "pkg/greet/greet_test.go")
+	if !w.handleFileEvent("greet_test.go", testFile, OpWrite, false) {
+		t.Error("test event processed by the test handler should count as processed")
+	}
+	w.handleFileEvent("root_test.go", filepath.Join(root, "root_test.go"), OpWrite, false)
+
+	if got := server.Files(); len(got) != 0 {
+		t.Errorf("build handler received %v; want no test files", got)
+	}
+	if got := runner.Files(); len(got) != 0 {
+		t.Errorf("NewFileEvent received %v; test files go to NewTestEvent", got)
+	}
+	want := []string{"greet_test.go@./pkg/greet:write", "root_test.go@.:write"}
+	if len(runner.tests) != 2 || runner.tests[0] != want[0] || runner.tests[1] != want[1] {
+		t.Errorf("test events = %v; want %v", runner.tests, want)
+	}
+
+	w.triggerBrowserReload()
+	if reloads != 0 {
+		t.Errorf("browser reloaded %d times; test runs never reload", reloads)
+	}
+	w.stopReload()
+}
+
+func TestHandleFileEvent_BlockingTestFailureShowsOverlay(t *testing.T) {
+	root, server, runner := newTestFilesProject(t)
+	runner.err = errors.New("--- FAIL: TestHi")
+	var overlays [][]HandlerFailure
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, runner},
+		BlockingHandlers:   []string{"tests"},
+		ErrorOverlay: func(failures []HandlerFailure) error {
+			overlays = append(overlays, failures)
+			return nil
+		},
+		Logger: func(message ...any) { t.Log(message...) },
+	})
+
+	w.handleFileEvent("greet_test.go", filepath.Join(root, "pkg/greet/greet_test.go"), OpWrite, false)
+	w.stopReload()
+	w.triggerBrowserReload()
+
+	if len(overlays) != 1 || len(overlays[0]) != 1 || overlays[0][0].Handler != "tests" {
+		t.Errorf("overlays = %v; want the test failure", overlays)
+	}
+}
+
+func TestHandleFileEvent_BuildTestFiles(t *testing.T) {
+	root, server, runner := newTestFilesProject(t)
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, runner},
+		BuildTestFiles:     true,
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	// the test file is routed through the dependency graph like any .go file
+	w.handleFileEvent("server_test.go", filepath.Join(root, "cmd/server/server_test.go"), OpWrite, false)
+	if len(runner.tests) != 0 {
+		t.Errorf("test events = %v; want none with BuildTestFiles", runner.tests)
+	}
+}
+
+func TestInitialRegistration_SkipsTestFiles(t *testing.T) {
+	root, server, runner := newTestFilesProject(t)
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, runner},
+		Logger:             func(message ...any) {},
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	w.watcher = watcher
+
+	w.InitialRegistration()
+
+	for _, f := range append(server.Files(), runner.Files()...) {
+		if isTestFile(strings.TrimSuffix(f, ":create")) {
+			t.Errorf("test file registered: %s", f)
+		}
+	}
+	if len(runner.tests) != 0 {
+		t.Errorf("test events on startup = %v; want none", runner.tests)
+	}
+}
+
+// AllGoFilesHandler receives every .go change, like a test runner
+type AllGoFilesHandler struct {
+	RecordingHandler
+}
+
+func (h *AllGoFilesHandler) AllGoFiles() bool { return true }
+
+func TestHandleFileEvent_AllGoFilesHandler(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, map[string]string{
+		"go.mod":               "module example\n\ngo 1.22\n",
+		"cmd/server/main.go":   "package main\n\nimport \"example/pkg/greet\"\n\nfunc main() { greet.Hi() }\n",
+		"pkg/greet/greet.go":   "package greet\n\nfunc Hi() {}\n",
+		"pkg/unused/unused.go": "package unused\n",
+	})
+	server := &RecordingHandler{Name_: "server", MainInput: "cmd/server/main.go", Extensions: []string{".go"}}
+	all := &AllGoFilesHandler{RecordingHandler{Name_: "tests", Extensions: []string{".go"}}}
+	w := New(&WatchConfig{
+		AppRootDir:         root,
+		FilesEventHandlers: []FilesEventHandlers{server, all},
+		Logger:             func(message ...any) { t.Log(message...) },
+	})
+	defer w.stopReload()
+
+	w.handleFileEvent("greet.go", filepath.Join(root, "pkg/greet/greet.go"), OpWrite, false)
+	w.handleFileEvent("unused.go", filepath.Join(root, "pkg/unused/unused.go"), OpWrite, false)
+
+	if got := all.Files(); len(got) != 2 {
+		t.Errorf("AllGoFiles handler received %v; want both files", got)
+	}
+	if got := server.Files(); len(got) != 1 || got[0] != "greet.go:write" {
+		t.Errorf("server received %v; want only its own package", got)
+	}
+	if got := w.OwnersOf(filepath.Join(root, "pkg/greet/greet.go")); len(got) != 1 || got[0] != "server" {
+		t.Errorf("OwnersOf(greet.go) = %v; want [server] only", got)
+	}
+}
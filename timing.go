@@ -0,0 +1,83 @@
+package devwatch
+
+import (
+	"time"
+)
+
+const (
+	defaultDebounce    = 50 * time.Millisecond
+	defaultReloadDelay = 50 * time.Millisecond
+)
+
+// DebounceMode selects when a burst of events on the same file is dispatched.
+type DebounceMode int
+
+const (
+	// DebounceDefault inherits the mode of the enclosing level (leading when unset).
+	DebounceDefault DebounceMode = iota
+	// DebounceLeading dispatches the first event at once and drops events with
+	// identical content that arrive within the window.
+	DebounceLeading
+	// DebounceTrailing waits until no event arrived for the window and then
+	// dispatches the last one.
+	DebounceTrailing
+)
+
+// Timing configures the debounce and browser reload windows.
+// Zero fields inherit from the enclosing level:
+// handler → WatchConfig.ExtensionTiming → WatchConfig.Timing → defaults (50ms, leading).
+type Timing struct {
+	Debounce    time.Duration // per-file window for duplicate events
+	ReloadDelay time.Duration // wait after the last successful event before reloading the browser
+	Mode        DebounceMode
+}
+
+// HandlerTiming is optionally implemented by a FilesEventHandlers to override
+// the timing of the extensions it supports.
+type HandlerTiming interface {
+	Timing() Timing
+}
+
+// merge returns t with the non-zero fields of o applied
+func (t Timing) merge(o Timing) Timing {
+	if o.Debounce > 0 {
+		t.Debounce = o.Debounce
+	}
+	if o.ReloadDelay > 0 {
+		t.ReloadDelay = o.ReloadDelay
+	}
+	if o.Mode != DebounceDefault {
+		t.Mode = o.Mode
+	}
+	return t
+}
+
+// timingFor resolves the timing of an extension for the given handlers.
+// Only handlers supporting the extension are considered. They share a single
+// event, so when several override the same field the longest window and the
+// trailing mode win.
+func (h *DevWatch) timingFor(extension string, handlers []FilesEventHandlers) Timing {
+	timing := Timing{
+		Debounce:    defaultDebounce,
+		ReloadDelay: defaultReloadDelay,
+		Mode:        DebounceLeading,
+	}.merge(h.Timing)
+
+	if ext, ok := h.ExtensionTiming[extension]; ok {
+		timing = timing.merge(ext)
+	}
+
+	var override Timing
+	for _, handler := range handlers {
+		ht, ok := handler.(HandlerTiming)
+		if !ok || !handlesExtension(handler, extension) {
+			continue
+		}
+		t := ht.Timing()
+		override.Debounce = max(override.Debounce, t.Debounce)
+		override.ReloadDelay = max(override.ReloadDelay, t.ReloadDelay)
+		override.Mode = max(override.Mode, t.Mode)
+	}
+
+	return timing.merge(override)
+}
@@ -0,0 +1,178 @@
+package devwatch
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TimedHandler is a SuccessHandler that overrides its timing
+type TimedHandler struct {
+	SuccessHandler
+	Timing_ Timing
+}
+
+func (h *TimedHandler) Timing() Timing {
+	return h.Timing_
+}
+
+func TestTimingFor(t *testing.T) {
+	css := &TimedHandler{
+		SuccessHandler: SuccessHandler{SupportedExtensions_: []string{".css"}},
+		Timing_:        Timing{ReloadDelay: 5 * time.Millisecond},
+	}
+	slowGo := &TimedHandler{
+		SuccessHandler: SuccessHandler{SupportedExtensions_: []string{".go"}},
+		Timing_:        Timing{Debounce: 300 * time.Millisecond},
+	}
+	trailingGo := &TimedHandler{
+		SuccessHandler: SuccessHandler{SupportedExtensions_: []string{".go"}},
+		Timing_:        Timing{Debounce: 100 * time.Millisecond, Mode: DebounceTrailing},
+	}
+
+	w := &DevWatch{WatchConfig: &WatchConfig{
+		Timing: Timing{Debounce: 200 * time.Millisecond},
+		ExtensionTiming: map[string]Timing{
+			".html": {ReloadDelay: 20 * time.Millisecond, Mode: DebounceTrailing},
+		},
+	}}
+	handlers := []FilesEventHandlers{css, slowGo, trailingGo}
+
+	tests := []struct {
+		name      string
+		extension string
+		handlers  []FilesEventHandlers
+		want      Timing
+	}{
+		{"global config over defaults", ".js", handlers,
+			Timing{Debounce: 200 * time.Millisecond, ReloadDelay: defaultReloadDelay, Mode: DebounceLeading}},
+		{"extension over global", ".html", handlers,
+			Timing{Debounce: 200 * time.Millisecond, ReloadDelay: 20 * time.Millisecond, Mode: DebounceTrailing}},
+		{"handler over global", ".css", handlers,
+			Timing{Debounce: 200 * time.Millisecond, ReloadDelay: 5 * time.Millisecond, Mode: DebounceLeading}},
+		{"longest window and trailing win", ".go", handlers,
+			Timing{Debounce: 300 * time.Millisecond, ReloadDelay: defaultReloadDelay, Mode: DebounceTrailing}},
+		{"only given handlers count", ".go", []FilesEventHandlers{slowGo},
+			Timing{Debounce: 300 * time.Millisecond, ReloadDelay: defaultReloadDelay, Mode: DebounceLeading}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.timingFor(tt.extension, tt.handlers); got != tt.want {
+				t.Errorf("timingFor(%q) = %+v; want %+v", tt.extension, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchEvents_TrailingDebounce(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := tempDir + "/style.css"
+
+	var calls int32
+	var reloads int64
+	w := New(&WatchConfig{
+		AppRootDir:         tempDir,
+		FilesEventHandlers: []FilesEventHandlers{&SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}},
+		Timing:             Timing{Debounce: 80 * time.Millisecond, Mode: DebounceTrailing},
+		BrowserReload: func() error {
+			atomic.AddInt64(&reloads, 1)
+			return nil
+		},
+		Logger:   func(message ...any) { t.Log(message...) },
+		ExitChan: make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+
+	// A burst of distinct edits, each inside the previous one's window
+	for i, content := range []string{"a {}", "b {}", "c {}"} {
+		if err := os.WriteFile(cssFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		watcher.Events <- fsnotify.Event{Name: cssFile, Op: fsnotify.Write}
+		if i < 2 {
+			time.Sleep(30 * time.Millisecond)
+		}
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("handler called %d times before the window was quiet; want 0", got)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("handler called %d times; want 1 for the whole burst", got)
+	}
+	if got := atomic.LoadInt64(&reloads); got != 1 {
+		t.Errorf("browser reloaded %d times; want 1", got)
+	}
+
+	w.ExitChan <- true
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchEvents did not exit in time")
+	}
+}
+
+func TestWatchEvents_ExtensionReloadDelay(t *testing.T) {
+	tempDir := t.TempDir()
+	cssFile := tempDir + "/style.css"
+	if err := os.WriteFile(cssFile, []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int32
+	reloaded := make(chan time.Time, 1)
+	w := New(&WatchConfig{
+		AppRootDir:         tempDir,
+		FilesEventHandlers: []FilesEventHandlers{&SuccessHandler{callCount: &calls, SupportedExtensions_: []string{".css"}}},
+		ExtensionTiming:    map[string]Timing{".css": {ReloadDelay: 250 * time.Millisecond}},
+		BrowserReload: func() error {
+			reloaded <- time.Now()
+			return nil
+		},
+		Logger:   func(message ...any) { t.Log(message...) },
+		ExitChan: make(chan bool, 1),
+	})
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	w.watcher = watcher
+
+	done := make(chan bool)
+	go func() {
+		w.watchEvents()
+		done <- true
+	}()
+
+	sent := time.Now()
+	watcher.Events <- fsnotify.Event{Name: cssFile, Op: fsnotify.Write}
+
+	select {
+	case at := <-reloaded:
+		if elapsed := at.Sub(sent); elapsed < 250*time.Millisecond {
+			t.Errorf("reload fired after %v; want at least the 250ms extension delay", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for BrowserReload")
+	}
+
+	w.ExitChan <- true
+	<-done
+}
@@ -1,41 +1,54 @@
 package devwatch
 
 import (
-	"crypto/sha256"
-	"io"
 	"os"
 	"path/filepath"
-	"slices"
-	"strings"
 	"time"
+
+	"github.com/tinywasm/depfind"
+)
+
+const (
+	defaultMaxTrackedFiles = 4096
+	defaultTrackedFileTTL  = 30 * time.Minute
 )
 
 // fileEventKey stores both time and content hash for smarter debouncing
 type fileEventKey struct {
-	lastTime time.Time
-	lastHash [32]byte
+	lastTime  time.Time
+	lastStamp fileStamp
+	lastHash  [32]byte
+}
+
+// dispatchedFile is the content at the last dispatch where at least one
+// handler succeeded. Kept apart from fileEventKey so TrackedFileTTL doesn't
+// expire it: SkipUnchangedWrites compares against it no matter the elapsed time
+type dispatchedFile struct {
+	stamp fileStamp
+	hash  [32]byte
+}
+
+// pendingEvent is the last event of a path waiting for its trailing debounce window
+type pendingEvent struct {
+	fileName      string
+	eventType     string
+	isDeleteEvent bool
 }
 
 func (h *DevWatch) watchEvents() {
 	// Track last event with content hash for smart debouncing
-	// This allows rapid edits while filtering duplicate OS events
-	lastEventInfo := make(map[string]fileEventKey)
-	const debounceWindow = 50 * time.Millisecond // Reduced for faster response
-
-	// create a stopped reload timer and a single goroutine that will handle its firing.
-	h.reloadMutex.Lock()
-	if h.reloadTimer == nil {
-		h.reloadTimer = time.NewTimer(0)
-		h.reloadTimer.Stop()
-		// goroutine to wait on timer events and invoke reload
-		go func(t *time.Timer) {
-			for {
-				<-t.C
-				h.triggerBrowserReload()
-			}
-		}(h.reloadTimer)
-	}
-	h.reloadMutex.Unlock()
+	// This allows rapid edits while filtering duplicate OS events.
+	// The state is bounded (LRU + TTL) so long sessions don't leak memory.
+	h.initFileState()
+	lastEventInfo := h.eventState
+
+	// TRAILING DEBOUNCE: the last event of a path is dispatched from this
+	// loop once no other event arrived for its window
+	trailing := make(chan string)
+	stop := make(chan struct{})
+	defer close(stop)
+	pending := make(map[string]pendingEvent)
+	timers := make(map[string]*time.Timer)
 
 	for {
 		select {
@@ -46,8 +59,23 @@ func (h *DevWatch) watchEvents() {
 				return
 			}
 
-			// create, write, rename, remove
-			eventType := strings.ToLower(event.Op.String())
+			// GIT: markers in the git dir start and end a quiet period
+			if h.gitEvent(event.Name) {
+				continue
+			}
+
+			// create, write, rename, remove, chmod
+			eventType := opName(event.Op)
+
+			// A .go file renamed away may have been the last of its package: the
+			// old path is gone and the event dropped below, so update the graph first
+			if eventType == OpRename && packageChanged(event.Name, eventType) {
+				h.invalidateDeps()
+			}
+
+			if !h.eventAllowed(eventType) {
+				continue // Filtered before any stat, hashing or dispatch
+			}
 			isDeleteEvent := eventType == "remove" || eventType == "delete"
 
 			// For non-delete events, check if file exists and is not contained
@@ -72,16 +100,41 @@ func (h *DevWatch) watchEvents() {
 				continue
 			}
 
+			// PAUSED: keep the path, its final state is dispatched on Resume
+			if h.hold(fileName, event.Name, eventType) {
+				continue
+			}
+
+			timing := h.timingFor(filepath.Ext(event.Name), h.FilesEventHandlers)
+
+			if timing.Mode == DebounceTrailing {
+				// Keep only the latest event and restart the path's window
+				path := event.Name
+				pending[path] = pendingEvent{fileName: fileName, eventType: eventType, isDeleteEvent: isDeleteEvent}
+				if t, exists := timers[path]; exists {
+					t.Stop()
+				}
+				timers[path] = time.AfterFunc(timing.Debounce, func() {
+					select {
+					case trailing <- path:
+					case <-stop:
+					}
+				})
+				continue
+			}
+
 			// SMART DEBOUNCE: Filter duplicate OS events but allow rapid user edits
 			// Strategy: Compare both time AND file content hash
 			now := time.Now()
 			shouldProcess := true
 
-			if lastInfo, exists := lastEventInfo[event.Name]; exists {
+			if lastInfo, exists := lastEventInfo.Get(event.Name); exists {
 				timeSinceLastEvent := now.Sub(lastInfo.lastTime)
 
-				// If event is very recent (< 50ms), check if content changed
-				if timeSinceLastEvent <= debounceWindow {
+				// If event is very recent (within the debounce window), check if content changed.
+				// METADATA FAST PATH: a different size or mtime already means
+				// a real edit, so the file is only hashed when both match.
+				if timeSinceLastEvent <= timing.Debounce && (isDeleteEvent || newFileStamp(event.Name, info) == lastInfo.lastStamp) {
 					// Calculate current file hash
 					currentHash := h.calculateFileHash(event.Name)
 
@@ -99,20 +152,33 @@ func (h *DevWatch) watchEvents() {
 				continue // Skip duplicate event
 			}
 
-			// Handle file events (both delete and non-delete)
-			// NOTE: This call blocks during compilation! Events arriving during
-			// compilation will queue up in the watcher.Events channel.
-			h.handleFileEvent(fileName, event.Name, eventType, isDeleteEvent)
-
-			// Record event with content hash AFTER processing
-			// This ensures the hash reflects the file state after compilation/processing
-			// FIX: Previously this was done BEFORE handleFileEvent, causing rapid edits
-			// to be incorrectly detected as duplicates because the hash was captured
-			// before the file was actually modified by the compilation process.
-			lastEventInfo[event.Name] = fileEventKey{
-				lastTime: now,
-				lastHash: h.calculateFileHash(event.Name),
+			h.dispatchFileEvent(fileName, event.Name, eventType, isDeleteEvent, info, now)
+
+		case path := <-trailing:
+			ev, exists := pending[path]
+			if !exists {
+				continue // Already dispatched by an earlier timer
+			}
+			delete(pending, path)
+			delete(timers, path)
+
+			// PAUSED since the event arrived: it joins the held batch
+			if h.hold(ev.fileName, path, ev.eventType) {
+				continue
+			}
+
+			// The file may have changed state while its window was open
+			var info os.FileInfo
+			if !ev.isDeleteEvent {
+				var statErr error
+				if info, statErr = os.Stat(path); statErr != nil {
+					continue
+				}
 			}
+			h.dispatchFileEvent(ev.fileName, path, ev.eventType, ev.isDeleteEvent, info, time.Now())
+
+		case <-h.resumed():
+			h.flushHeld()
 
 		case err, ok := <-h.watcher.Errors:
 			if !ok {
@@ -121,6 +187,10 @@ func (h *DevWatch) watchEvents() {
 			}
 
 		case <-h.ExitChan:
+			for _, t := range timers {
+				t.Stop()
+			}
+			h.stopGitTimer()
 			h.watcher.Close()
 			h.stopReload()
 			return
@@ -128,6 +198,63 @@ func (h *DevWatch) watchEvents() {
 	}
 }
 
+// dispatchFileEvent runs a debounced file event through the handlers and
+// records the resulting file state for the next debounce decision
+func (h *DevWatch) dispatchFileEvent(fileName, eventName, eventType string, isDeleteEvent bool, info os.FileInfo, now time.Time) {
+	lastEventInfo := h.eventState
+
+	// SELF WRITES: outputs of a handler must not retrigger the handlers
+	if h.selfWrite(eventName) {
+		return
+	}
+
+	// FEEDBACK LOOPS: handler writes that keep triggering handlers are paused
+	if h.loopBroken(eventName, info) {
+		return
+	}
+	defer h.clearChain()
+
+	// CONTENT IDENTITY: skip writes that leave the content as it was
+	// at the last successful dispatch, regardless of elapsed time
+	if h.SkipUnchangedWrites && eventType == OpWrite {
+		// a different size is a change for sure, skip hashing
+		if last, exists := h.dispatchedState.Get(eventName); exists && info.Size() == last.stamp.size {
+			if h.calculateFileHash(eventName) == last.hash {
+				return
+			}
+		}
+	}
+	// the content the handlers are about to see: a save during a slow build
+	// must not be recorded as dispatched, its queued event still has to run
+	var seen dispatchedFile
+	if h.SkipUnchangedWrites && !isDeleteEvent {
+		seen.stamp, seen.hash = h.fileSnapshot(eventName)
+	}
+
+	// Handle file events (both delete and non-delete)
+	// NOTE: This call blocks during compilation! Events arriving during
+	// compilation will queue up in the watcher.Events channel.
+	dispatched := h.handleFileEvent(fileName, eventName, eventType, isDeleteEvent)
+
+	// Record event with content hash AFTER processing
+	// This ensures the hash reflects the file state after compilation/processing
+	// FIX: Previously this was done BEFORE handleFileEvent, causing rapid edits
+	// to be incorrectly detected as duplicates because the hash was captured
+	// before the file was actually modified by the compilation process.
+	if isDeleteEvent {
+		lastEventInfo.Delete(eventName) // removed files leave no state behind
+		h.dispatchedState.Delete(eventName)
+		return
+	}
+	record, _ := lastEventInfo.Get(eventName)
+	record.lastTime = now
+	record.lastStamp, record.lastHash = h.fileSnapshot(eventName)
+	lastEventInfo.Put(eventName, record)
+	if dispatched && h.SkipUnchangedWrites {
+		h.dispatchedState.Put(eventName, seen)
+	}
+}
+
 // handleDirectoryEvent processes directory creation/modification events
 func (h *DevWatch) handleDirectoryEvent(fileName, eventName, eventType string) {
 	if h.FolderEvents != nil {
@@ -139,6 +266,11 @@ func (h *DevWatch) handleDirectoryEvent(fileName, eventName, eventType string) {
 
 	// Add new directory to watcher
 	if eventType == "create" {
+		// A directory moved into the tree may bring new packages
+		if goFilesIn(eventName) > 0 {
+			h.invalidateDeps()
+		}
+
 		// Create a registry map for the new directory walk
 		reg := make(map[string]struct{})
 
@@ -162,16 +294,41 @@ func (h *DevWatch) handleDirectoryEvent(fileName, eventName, eventType string) {
 	}
 }
 
-// handleFileEvent processes file creation/modification/deletion events
-func (h *DevWatch) handleFileEvent(fileName, eventName, eventType string, isDeleteEvent bool) {
+// handleFileEvent processes file creation/modification/deletion events.
+// It reports whether at least one handler processed the event successfully.
+func (h *DevWatch) handleFileEvent(fileName, eventName, eventType string, isDeleteEvent bool) bool {
+	// Test files can't change a binary: only test handlers get them
+	if isTestFile(eventName) && !h.BuildTestFiles {
+		return h.handleTestFile(eventName, eventType)
+	}
+
 	extension := filepath.Ext(eventName)
 	var processedSuccessfully bool
-	isGoFileEvent := extension == ".go"
-	var atLeastOneGoHandlerSucceeded bool
+	var results handlerResults
+	var graphUpdated bool // a handler fed this event to the dependency graph
+
+	// Module layout changes make the dependency graph stale: rebuild it
+	// before any ownership query of this event
+	if isModuleFile(eventName) {
+		processedSuccessfully = h.handleModuleChange(eventName, eventType)
+	} else if packageChanged(eventName, eventType) {
+		h.invalidateDeps()
+	}
+
+	// Generated code must be up to date before the Go handlers build
+	h.runGenerators(eventName)
 
 	// Execute ALL handlers, don't stop on errors
 	for _, handler := range h.FilesEventHandlers {
-		if !slices.Contains(handler.SupportedExtensions(), extension) {
+		if !handlesExtension(handler, extension) {
+			continue
+		}
+		if !handlerAcceptsEvent(handler, eventType) || h.loopPaused(handler) {
+			continue
+		}
+
+		// Files excluded from the handler's GOOS/GOARCH/tags never reach it
+		if extension == ".go" && !buildMatches(handler, eventName) {
 			continue
 		}
 
@@ -179,112 +336,129 @@ func (h *DevWatch) handleFileEvent(fileName, eventName, eventType string, isDele
 		var isMine = true
 		var herr error
 
-		if !isDeleteEvent && extension == ".go" {
-			isMine, herr = h.depFinder.ThisFileIsMine(handler.MainInputFileRelativePath(), eventName, eventType)
+		if !isDeleteEvent && extension == ".go" && !allGoFiles(handler) {
+			h.withDeps(func(finder *depfind.GoDepFind) {
+				isMine, herr = finder.ThisFileIsMine(handler.MainInputFileRelativePath(), eventName, eventType)
+			})
 			if herr != nil {
 				// h.Logger("DEBUG Error from ThisFileIsMine, continuing: %v\n", herr)
 				continue
 			}
+			graphUpdated = true
 		}
 
-		if isMine {
-			err := handler.NewFileEvent(fileName, extension, eventName, eventType)
-			if err != nil {
-				//h.Logger("DEBUG Watch updating file error:", err)
-				// Continue to next handler even if this one failed
-			} else {
+		if isMine && !h.deferBuild(handler, fileName, extension, eventName, eventType) {
+			reload, err := h.runHandler(handler, fileName, extension, eventName, eventType)
+			if err == nil {
 				// Track success for both Go and non-Go files
 				processedSuccessfully = true
-				if isGoFileEvent {
-					atLeastOneGoHandlerSucceeded = true
-				}
 			}
+			// Continue to next handler even if this one failed
+			h.recordResult(&results, handler, eventName, reload, err)
 		}
 	}
 
-	// Schedule reload if AT LEAST ONE handler succeeded
-	// For Go files: reload if any handler succeeded
-	// For non-Go files: reload if any handler succeeded
-	if (isGoFileEvent && atLeastOneGoHandlerSucceeded) || (!isGoFileEvent && processedSuccessfully) {
-		h.scheduleReload()
+	// Embedded files (//go:embed) also go to the Go handlers owning the
+	// package that embeds them, unless they already handled the extension
+	if extension != ".go" && !isModuleFile(eventName) {
+		for _, handler := range h.embedOwners(eventName) {
+			if handlesExtension(handler, extension) || !handlerAcceptsEvent(handler, eventType) || h.loopPaused(handler) {
+				continue
+			}
+			if h.deferBuild(handler, fileName, extension, eventName, eventType) {
+				continue
+			}
+			reload, err := h.runHandler(handler, fileName, extension, eventName, eventType)
+			if err == nil {
+				processedSuccessfully = true
+			}
+			h.recordResult(&results, handler, eventName, reload, err)
+		}
+	} else if extension == ".go" {
+		h.refreshEmbeds(eventName)
+		h.refreshDirectives(eventName)
 	}
-}
 
-// triggerBrowserReload safely triggers a browser reload in a goroutine
-func (h *DevWatch) triggerBrowserReload() {
-	if h.BrowserReload != nil {
-		// Call synchronously so the caller (watchEvents) completes the
-		// reload action before returning. This prevents background reload
-		// goroutines from racing with test teardown and shared counters.
-		_ = h.BrowserReload()
+	// Cached ownership answers are stale once imports change. Without a Go
+	// handler nothing updated the graph, so rebuild it too.
+	if h.importsChanged(eventName, eventType) {
+		if graphUpdated {
+			h.ownerCache.Clear()
+		} else {
+			h.invalidateDeps()
+		}
 	}
-}
-
-// scheduleReload resets or starts a reload timer which will call triggerBrowserReload
-// after a short debounce period. This mirrors the original implementation's
-// behavior of resetting the timer on each new event so only the last one triggers reload.
-func (h *DevWatch) scheduleReload() {
-	const wait = 50 * time.Millisecond
 
-	h.reloadMutex.Lock()
-	defer h.reloadMutex.Unlock()
+	h.scheduleResults(eventName, extension, results)
 
-	if h.reloadTimer == nil {
-		h.reloadTimer = time.NewTimer(wait)
-		return
-	}
+	return processedSuccessfully
+}
 
-	// Stop existing timer and reset
-	if !h.reloadTimer.Stop() {
-		select {
-		case <-h.reloadTimer.C:
-		default:
-		}
-	}
-	h.reloadTimer.Reset(wait)
+// handlerResults collects the outcome of the handlers run for one event
+type handlerResults struct {
+	reloaders   []FilesEventHandlers // handlers whose reload policy asks for a reload
+	blockingRan bool
+	failures    []HandlerFailure
+	passed      []string // blocking handlers that succeeded
+	succeeded   []string // every handler that succeeded
 }
 
-// stopReload stops and clears the reload timer; used during shutdown
-func (h *DevWatch) stopReload() {
-	h.reloadMutex.Lock()
-	defer h.reloadMutex.Unlock()
-	if h.reloadTimer != nil {
-		// Only trigger reload if timer was actually programmed (not stopped)
-		// Check if there's a pending reload by trying to stop the timer
-		if !h.reloadTimer.Stop() {
-			// Timer already fired or was never started, check channel
-			select {
-			case <-h.reloadTimer.C:
-				// Timer fired but reload not yet called, trigger it now
-				h.reloadMutex.Unlock() // Unlock before calling reload to avoid deadlock
-				h.triggerBrowserReload()
-				h.reloadMutex.Lock() // Re-lock before returning
-			default:
-				// Timer was stopped or never programmed, don't reload
-			}
+// recordResult adds the outcome of one handler to results
+func (h *DevWatch) recordResult(results *handlerResults, handler FilesEventHandlers, eventName string, reload bool, err error) {
+	if reload {
+		results.reloaders = append(results.reloaders, handler)
+	}
+	if err == nil {
+		results.succeeded = appendUnique(results.succeeded, handlerName(handler))
+	}
+	if h.isBlocking(handler) {
+		results.blockingRan = true
+		if err != nil {
+			results.failures = append(results.failures, HandlerFailure{Handler: handlerName(handler), Path: eventName, Error: err.Error()})
+		} else {
+			results.passed = append(results.passed, handlerName(handler))
 		}
-		// If Stop() returned true, timer was active and is now stopped
-		// Don't trigger reload in this case
 	}
 }
 
-// calculateFileHash computes SHA256 hash of file content for smart debouncing
-// Returns zero hash if file cannot be read (will be treated as different)
-func (h *DevWatch) calculateFileHash(filePath string) [32]byte {
-	var zeroHash [32]byte
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return zeroHash // File doesn't exist or can't be read
+// scheduleResults batches the results of an event into the matching reload targets.
+// A reload is scheduled if AT LEAST ONE handler asked for it through its policy
+// (by default: when it succeeded). Backend handlers with ReloadNever
+// don't reload the browser but still reach AfterBuild. Blocking handlers always
+// reach the coordinator so their failures show up and are cleared later.
+func (h *DevWatch) scheduleResults(eventName, extension string, results handlerResults) {
+	if len(results.reloaders) == 0 && len(results.succeeded) == 0 && !results.blockingRan {
+		return
 	}
-	defer file.Close()
 
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, file); err != nil {
-		return zeroHash // Error reading file
+	var ran []string
+	for _, handler := range results.reloaders {
+		ran = append(ran, handlerName(handler))
+	}
+	ran = appendUnique(ran, results.succeeded...)
+	for _, f := range results.failures {
+		ran = appendUnique(ran, f.Handler)
 	}
 
-	var hash [32]byte
-	copy(hash[:], hasher.Sum(nil))
-	return hash
+	// Each target batches and debounces its own changes
+	for _, t := range h.targetsFor(eventName, ran) {
+		t.mu.Lock()
+		if len(results.reloaders) > 0 {
+			t.batch.add(eventName, extension, results.reloaders)
+		}
+		t.batch.built = appendUnique(t.batch.built, results.succeeded...)
+		for _, name := range results.passed {
+			t.batch.pass(name)
+		}
+		for _, f := range results.failures {
+			t.batch.fail(f)
+		}
+		t.mu.Unlock()
+
+		wait := h.timingFor(extension, results.reloaders).ReloadDelay
+		if t.ReloadDelay > 0 {
+			wait = t.ReloadDelay
+		}
+		t.scheduler.schedule(wait)
+	}
 }